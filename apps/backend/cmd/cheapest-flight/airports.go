@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"cheapest-flight-backend/config"
+	"cheapest-flight-backend/logging"
+	"cheapest-flight-backend/services"
+)
+
+func runAirports(args []string, stdout io.Writer) error {
+	fs := newFlagSet("airports", "airports QUERY")
+	var common commonFlags
+	common.register(fs)
+	limit := fs.Int("limit", 20, "show at most this many airports, best matches first")
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		fs.Usage()
+		return fmt.Errorf("%w: airports needs a QUERY", errUsage)
+	}
+	if err := common.validate(); err != nil {
+		return err
+	}
+	if *limit < 1 || *limit > 100 {
+		return fmt.Errorf("%w: --limit must be between 1 and 100", errUsage)
+	}
+	query := strings.Join(positional, " ")
+
+	var airports []services.Airport
+	if common.server != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if airports, err = newClient(common.server, common.apiKey).airports(ctx, query, *limit); err != nil {
+			return err
+		}
+	} else {
+		airportService, err := services.NewAirportService(localAirportsFile())
+		if err != nil {
+			return err
+		}
+		airports, _ = airportService.SearchAirports(query, services.AirportFilter{}, *limit, 0)
+	}
+
+	return writeAirports(stdout, common.format, airports)
+}
+
+// localAirportsFile returns the configured AIRPORTS_FILE. Listing airports
+// needs no Amadeus credentials, so an otherwise invalid configuration falls
+// back to the built-in airport data with a warning
+func localAirportsFile() string {
+	slog.SetDefault(logging.New(os.Stderr, slog.LevelWarn))
+	cfg, err := config.Load(nil)
+	if err != nil {
+		slog.Warn("using the built-in airport data", "error", err)
+		return ""
+	}
+	return cfg.AirportsFile
+}
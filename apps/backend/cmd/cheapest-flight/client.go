@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"cheapest-flight-backend/middleware"
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/services"
+)
+
+// client calls the HTTP API of a running server
+type client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func newClient(baseURL, apiKey string) *client {
+	return &client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{},
+	}
+}
+
+func (c *client) search(ctx context.Context, req models.FlightSearchRequest) (*models.FlightSearchResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var resp models.FlightSearchResponse
+	if err := c.do(ctx, http.MethodPost, "/api/search", bytes.NewReader(body), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (c *client) airports(ctx context.Context, query string, limit int) ([]services.Airport, error) {
+	var resp struct {
+		Airports []services.Airport `json:"airports"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/airports?q="+url.QueryEscape(query)+"&limit="+strconv.Itoa(limit), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Airports, nil
+}
+
+// do sends a request and decodes a successful JSON response into out. Error
+// responses are turned into errors carrying the server's message.
+func (c *client) do(ctx context.Context, method, path string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set(middleware.APIKeyHeader, c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr models.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Message == "" {
+			return fmt.Errorf("server returned %s", resp.Status)
+		}
+		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+			return fmt.Errorf("server returned %s: %s (retry after %ss)", resp.Status, apiErr.Message, retryAfter)
+		}
+		return fmt.Errorf("server returned %s: %s", resp.Status, apiErr.Message)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
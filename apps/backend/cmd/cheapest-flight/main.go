@@ -0,0 +1,111 @@
+// Command cheapest-flight searches for flights from the terminal, either by
+// running the route optimizer in-process or by calling a running server.
+//
+// Usage:
+//
+//	cheapest-flight search BKK LHR 2026-12-01 --passengers 2 --format table
+//	cheapest-flight airports bangkok --format csv
+//	cheapest-flight search BKK LHR 2026-12-01 --server http://localhost:8080
+//
+// Without --server (or CHEAPEST_FLIGHT_SERVER) searches call Amadeus
+// directly and need the same AMADEUS_* environment variables as the server.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage: cheapest-flight <command> [arguments] [flags]
+
+Commands:
+  search ORIGIN DESTINATION DATE   find the cheapest flights
+  airports QUERY                   look up airports by code, name or city
+
+Run "cheapest-flight <command> --help" for the flags of each command.
+`
+
+// errUsage marks errors caused by bad arguments, which exit with status 2
+var errUsage = errors.New("usage error")
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "cheapest-flight:", err)
+		}
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return flag.ErrHelp
+	}
+
+	switch args[0] {
+	case "search":
+		return runSearch(args[1:], stdout)
+	case "airports", "airport":
+		return runAirports(args[1:], stdout)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, args[0])
+	}
+}
+
+// commonFlags are shared by every command
+type commonFlags struct {
+	format string
+	server string
+	apiKey string
+}
+
+func (c *commonFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.format, "format", "table", "output format: table, json or csv")
+	fs.StringVar(&c.server, "server", os.Getenv("CHEAPEST_FLIGHT_SERVER"), "base URL of a running server; empty runs the search locally")
+	fs.StringVar(&c.apiKey, "api-key", os.Getenv("CHEAPEST_FLIGHT_API_KEY"), "API key sent to the server")
+}
+
+func (c *commonFlags) validate() error {
+	switch c.format {
+	case formatTable, formatJSON, formatCSV:
+		return nil
+	default:
+		return fmt.Errorf("%w: --format must be table, json or csv", errUsage)
+	}
+}
+
+// parseInterspersed parses fs allowing flags before, between and after the
+// positional arguments, which the flag package alone stops at
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cheapest-flight %s [flags]\n\nFlags:\n", synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
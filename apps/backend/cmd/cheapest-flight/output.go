@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/services"
+)
+
+// Output formats
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+func writeFlights(w io.Writer, format string, resp *models.FlightSearchResponse) error {
+	switch format {
+	case formatJSON:
+		return writeJSON(w, resp)
+	case formatCSV:
+		rows := [][]string{{"id", "origin", "destination", "date", "price", "currency", "airline", "duration", "stops", "route", "tags"}}
+		for _, f := range resp.Flights {
+			rows = append(rows, []string{
+				f.ID, f.Origin, f.Destination, f.Date,
+				strconv.FormatFloat(f.Price, 'f', 2, 64), f.Currency,
+				f.Airline, f.Duration, strconv.Itoa(f.Stops), strings.Join(f.Route, "-"), strings.Join(f.Tags, " "),
+			})
+		}
+		return writeCSV(w, rows)
+	default:
+		if len(resp.Flights) == 0 {
+			_, err := fmt.Fprintln(w, "No flights found")
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "PRICE\tSTOPS\tROUTE\tAIRLINE\tDURATION\tTAGS")
+		for _, f := range resp.Flights {
+			fmt.Fprintf(tw, "%.2f %s\t%d\t%s\t%s\t%s\t%s\n", f.Price, f.Currency, f.Stops, strings.Join(f.Route, " → "), f.Airline, f.Duration, strings.Join(f.Tags, ", "))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		summary := fmt.Sprintf("\n%d flights, %d upstream calls", resp.Total, resp.UpstreamCalls)
+		if resp.Degraded {
+			summary += " (partial results: daily search quota reached)"
+		}
+		_, err := fmt.Fprintln(w, summary)
+		return err
+	}
+}
+
+func writeAirports(w io.Writer, format string, airports []services.Airport) error {
+	switch format {
+	case formatJSON:
+		if airports == nil {
+			airports = []services.Airport{}
+		}
+		return writeJSON(w, airports)
+	case formatCSV:
+		rows := [][]string{{"iata", "icao", "name", "region", "country", "latitude", "longitude"}}
+		for _, a := range airports {
+			rows = append(rows, []string{a.IATA, a.ICAO, a.AirportName, a.RegionName, a.CountryCode,
+				strconv.FormatFloat(a.Latitude, 'f', -1, 64), strconv.FormatFloat(a.Longitude, 'f', -1, 64)})
+		}
+		return writeCSV(w, rows)
+	default:
+		if len(airports) == 0 {
+			_, err := fmt.Fprintln(w, "No airports found")
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "IATA\tICAO\tNAME\tREGION\tCOUNTRY")
+		for _, a := range airports {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", a.IATA, a.ICAO, a.AirportName, a.RegionName, a.CountryCode)
+		}
+		return tw.Flush()
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeCSV(w io.Writer, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
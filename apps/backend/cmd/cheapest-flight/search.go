@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"cheapest-flight-backend/config"
+	"cheapest-flight-backend/internal/wiring"
+	"cheapest-flight-backend/logging"
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/utils"
+)
+
+func runSearch(args []string, stdout io.Writer) error {
+	fs := newFlagSet("search", "search ORIGIN DESTINATION DATE")
+	var common commonFlags
+	common.register(fs)
+	passengers := fs.Int("passengers", 1, "number of adult passengers (1-9)")
+	nearbyKm := fs.Int("nearby-km", 0, "also search airports within this many km of ORIGIN and DESTINATION")
+	timeout := fs.Duration("timeout", 2*time.Minute, "give up after this long")
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 3 {
+		fs.Usage()
+		return fmt.Errorf("%w: search needs ORIGIN, DESTINATION and DATE", errUsage)
+	}
+	if err := common.validate(); err != nil {
+		return err
+	}
+
+	req := models.FlightSearchRequest{
+		Origin:         utils.NormalizeAirportCode(positional[0]),
+		Destination:    utils.NormalizeAirportCode(positional[1]),
+		Date:           positional[2],
+		Passengers:     *passengers,
+		NearbyRadiusKm: *nearbyKm,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var resp *models.FlightSearchResponse
+	if common.server != "" {
+		resp, err = newClient(common.server, common.apiKey).search(ctx, req)
+	} else {
+		resp, err = searchLocally(ctx, req)
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeFlights(stdout, common.format, resp)
+}
+
+// searchLocally runs the route optimizer in this process
+func searchLocally(ctx context.Context, req models.FlightSearchRequest) (*models.FlightSearchResponse, error) {
+	// Keep stdout for results; only warnings and errors reach the terminal
+	slog.SetDefault(logging.New(os.Stderr, slog.LevelWarn))
+
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	cfg, err := config.Load(nil)
+	if err != nil {
+		return nil, fmt.Errorf("local search needs Amadeus credentials (or use --server): %w", err)
+	}
+	airportService, err := services.NewAirportService(cfg.AirportsFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range []string{req.Origin, req.Destination} {
+		if !airportService.ValidateLocationCode(code) {
+			return nil, fmt.Errorf("%w: unknown airport or city code %s", errUsage, code)
+		}
+	}
+
+	budget := services.NewCallBudget(cfg.AmadeusMaxConcurrency, cfg.AmadeusDailyQuota)
+	amadeusService := services.NewAmadeusService(cfg.AmadeusBaseURL, wiring.AmadeusCredentials(cfg), cfg.AmadeusCredentialStrategy, budget)
+	amadeusService.Currency = cfg.Currency
+	amadeusService.HTTPClient.Timeout = cfg.AmadeusTimeout
+	optimizer := services.NewRouteOptimizer(amadeusService, airportService, nil, wiring.SearchSettings(cfg))
+
+	ctx, stats := services.WithSearchStats(ctx)
+	flights, err := optimizer.OptimizeRoutes(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &models.FlightSearchResponse{
+		Flights:       flights,
+		Total:         len(flights),
+		Query:         req,
+		UpstreamCalls: stats.Calls(),
+		Degraded:      stats.Rejected() > 0,
+	}, nil
+}
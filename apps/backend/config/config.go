@@ -1,43 +1,741 @@
 package config
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
 	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"cheapest-flight-backend/logging"
+	"cheapest-flight-backend/utils"
 )
 
+// Config is the backend's configuration. Each field can be set in the YAML
+// config file under its yaml key, by the environment variable of the same
+// name in upper case, or by a flag of the same name with dashes. Secrets have
+// no flag but can be read from the file named by their _FILE variable, such
+// as AMADEUS_API_SECRET_FILE.
 type Config struct {
-	Port             string
-	AmadeusAPIKey    string
-	AmadeusAPISecret string
-	AmadeusBaseURL   string
-	Environment      string
-	AllowedOrigins   []string
+	Port             string `yaml:"port"`
+	AmadeusAPIKey    string `yaml:"amadeus_api_key"`
+	AmadeusAPISecret string `yaml:"amadeus_api_secret"`
+	AmadeusBaseURL   string `yaml:"amadeus_base_url"`
+
+	// Extra Amadeus credential sets, tried after the one above, and whether
+	// to fail over between them in order or rotate through them
+	AmadeusCredentials        []Credentials `yaml:"amadeus_credentials"`
+	AmadeusCredentialStrategy string        `yaml:"amadeus_credential_strategy"`
+
+	Environment    string   `yaml:"environment"`
+	AllowedOrigins []string `yaml:"allowed_origins"`
+
+	// HTTP server timeouts
+	ServerReadTimeout  time.Duration `yaml:"server_read_timeout"`
+	ServerWriteTimeout time.Duration `yaml:"server_write_timeout"`
+	ServerIdleTimeout  time.Duration `yaml:"server_idle_timeout"`
+	ShutdownTimeout    time.Duration `yaml:"shutdown_timeout"`
+
+	// Outbound Amadeus call budget shared by all searches
+	AmadeusMaxConcurrency int           `yaml:"amadeus_max_concurrency"`
+	AmadeusDailyQuota     int           `yaml:"amadeus_daily_quota"` // 0 disables the quota
+	AmadeusTimeout        time.Duration `yaml:"amadeus_timeout"`
+	Currency              string        `yaml:"currency"`
+
+	// Route optimizer: hubs tried for connections, how many searches run at
+	// once and how many results are kept
+	SearchTimeout      time.Duration       `yaml:"search_timeout"`
+	HubAirports        map[string][]string `yaml:"hub_airports"`
+	MajorHubs          []string            `yaml:"major_hubs"`
+	MaxRelevantHubs    int                 `yaml:"max_relevant_hubs"`
+	MaxTwoStopHubs     int                 `yaml:"max_two_stop_hubs"`
+	OneStopConcurrency int                 `yaml:"one_stop_concurrency"`
+	TwoStopConcurrency int                 `yaml:"two_stop_concurrency"`
+	TopResults         int                 `yaml:"top_results"`
+	MaxNearbyAirports  int                 `yaml:"max_nearby_airports"`
+
+	// Destinations searched by /api/explore when Amadeus flight inspiration
+	// is unavailable, how many departure dates are tried for each, how many
+	// of those searches run at once and how many one request may make
+	ExploreDestinations []string `yaml:"explore_destinations"`
+	ExploreSampleDates  int      `yaml:"explore_sample_dates"`
+	ExploreConcurrency  int      `yaml:"explore_concurrency"`
+	ExploreMaxCalls     int      `yaml:"explore_max_calls"`
+
+	// Asynchronous search jobs
+	SearchJobWorkers int           `yaml:"search_job_workers"`
+	SearchJobQueue   int           `yaml:"search_job_queue"`
+	SearchJobTimeout time.Duration `yaml:"search_job_timeout"`
+	SearchJobTTL     time.Duration `yaml:"search_job_ttl"`
+
+	// Persistence backend ("sqlite" or "memory") and SQLite database file
+	DatabaseDriver string `yaml:"database_driver"`
+	DatabasePath   string `yaml:"database_path"`
+
+	// Airport data CSV file; empty uses the copy built into the binary.
+	// POST /api/admin/airports/reload rereads it.
+	AirportsFile string `yaml:"airports_file"`
+
+	// Price watch scheduler
+	WatchInterval      time.Duration `yaml:"watch_interval"`
+	WatchBudgetReserve int           `yaml:"watch_budget_reserve"` // daily calls kept back for interactive searches
+
+	// Outbound webhook delivery. Private, loopback and link-local
+	// destinations are refused unless explicitly allowed, which is meant for
+	// testing against a local receiver.
+	WebhookMaxAttempts              int           `yaml:"webhook_max_attempts"`
+	WebhookInitialBackoff           time.Duration `yaml:"webhook_initial_backoff"`
+	WebhookTimeout                  time.Duration `yaml:"webhook_timeout"`
+	WebhookAllowPrivateDestinations bool          `yaml:"webhook_allow_private_destinations"`
+
+	// User authentication. Without a secret, development servers sign tokens
+	// with a random key that changes on every restart.
+	JWTSecret string        `yaml:"jwt_secret"`
+	JWTTTL    time.Duration `yaml:"jwt_ttl"`
+
+	// Operator token for /api/admin endpoints; empty disables them
+	AdminToken string `yaml:"admin_token"`
+
+	// Limits for API keys issued without explicit ones
+	APIKeyRatePerMinute int `yaml:"api_key_rate_per_minute"`
+	APIKeyDailySearches int `yaml:"api_key_daily_searches"`
+
+	// Per-IP rate limiting for anonymous clients. A search spends
+	// RateLimitSearchCost tokens from a bucket of RateLimitBurst that refills
+	// at RateLimitPerMinute; 0 per minute disables the limit.
+	RateLimitPerMinute  int `yaml:"rate_limit_per_minute"`
+	RateLimitBurst      int `yaml:"rate_limit_burst"`
+	RateLimitSearchCost int `yaml:"rate_limit_search_cost"`
+
+	// Proxies whose X-Forwarded-For headers are trusted, as IPs or CIDRs,
+	// and the same list parsed
+	TrustedProxyList []string     `yaml:"trusted_proxies"`
+	TrustedProxies   []*net.IPNet `yaml:"-"`
+
+	// Minimum level of the JSON logs (debug, info, warn or error)
+	LogLevel slog.Level `yaml:"log_level"`
+
+	// Span exporter ("none", "stdout" or "otlp") and the fraction of new
+	// traces to sample. The OTLP endpoint comes from the standard
+	// OTEL_EXPORTER_OTLP_ENDPOINT variable.
+	TracingExporter    string  `yaml:"tracing_exporter"`
+	TracingSampleRatio float64 `yaml:"tracing_sample_ratio"`
+}
+
+// Credentials is one Amadeus API key and secret
+type Credentials struct {
+	APIKey    string `yaml:"api_key"`
+	APISecret string `yaml:"api_secret"`
+}
+
+// ValidationError lists every problem found while loading a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%d configuration problem(s): %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+// reloadable are the yaml keys of settings that take effect on reload
+// without a restart
+var reloadable = map[string]bool{
+	"allowed_origins":         true,
+	"hub_airports":            true,
+	"major_hubs":              true,
+	"max_relevant_hubs":       true,
+	"max_two_stop_hubs":       true,
+	"one_stop_concurrency":    true,
+	"two_stop_concurrency":    true,
+	"top_results":             true,
+	"max_nearby_airports":     true,
+	"explore_destinations":    true,
+	"explore_sample_dates":    true,
+	"explore_concurrency":     true,
+	"explore_max_calls":       true,
+	"api_key_rate_per_minute": true,
+	"api_key_daily_searches":  true,
+	"rate_limit_per_minute":   true,
+	"rate_limit_burst":        true,
+	"rate_limit_search_cost":  true,
+	"log_level":               true,
+}
+
+// Defaults returns the built-in configuration
+func Defaults() *Config {
+	return &Config{
+		Port:                      "8080",
+		AmadeusBaseURL:            "https://test.api.amadeus.com",
+		AmadeusCredentialStrategy: "failover",
+		Environment:               "development",
+		AllowedOrigins:            []string{"http://localhost:3000", "http://frontend:3000"},
+		ServerReadTimeout:         30 * time.Second,
+		ServerWriteTimeout:        60 * time.Second,
+		ServerIdleTimeout:         120 * time.Second,
+		ShutdownTimeout:           30 * time.Second,
+		AmadeusMaxConcurrency:     10,
+		AmadeusDailyQuota:         2000,
+		AmadeusTimeout:            30 * time.Second,
+		Currency:                  "USD",
+		SearchTimeout:             60 * time.Second,
+		HubAirports:               defaultHubAirports(),
+		MajorHubs:                 defaultMajorHubs(),
+		MaxRelevantHubs:           20,
+		MaxTwoStopHubs:            10,
+		OneStopConcurrency:        5,
+		TwoStopConcurrency:        3,
+		TopResults:                10,
+		MaxNearbyAirports:         3,
+		ExploreDestinations:       defaultExploreDestinations(),
+		ExploreSampleDates:        3,
+		ExploreConcurrency:        5,
+		ExploreMaxCalls:           30,
+		SearchJobWorkers:          4,
+		SearchJobQueue:            100,
+		SearchJobTimeout:          2 * time.Minute,
+		SearchJobTTL:              30 * time.Minute,
+		DatabaseDriver:            "sqlite",
+		DatabasePath:              "data/cheapest-flight.db",
+		WatchInterval:             6 * time.Hour,
+		WatchBudgetReserve:        200,
+		WebhookMaxAttempts:        5,
+		WebhookInitialBackoff:     2 * time.Second,
+		WebhookTimeout:            10 * time.Second,
+		JWTTTL:                    24 * time.Hour,
+		APIKeyRatePerMinute:       60,
+		APIKeyDailySearches:       500,
+		RateLimitPerMinute:        60,
+		RateLimitBurst:            60,
+		RateLimitSearchCost:       10,
+		LogLevel:                  slog.LevelInfo,
+		TracingExporter:           "none",
+		TracingSampleRatio:        1,
+	}
+}
+
+// defaultHubAirports returns the built-in 1-stop hubs by region
+func defaultHubAirports() map[string][]string {
+	return map[string][]string{
+		"north_america": {
+			"JFK", "LAX", "ORD", "DFW", "ATL", "DEN", "SFO", "LAS", "SEA", "MIA",
+			"BOS", "IAH", "PHX", "CLT", "MCO", "MSP", "DTW", "PHL", "LGA", "BWI",
+		},
+		"europe": {
+			"LHR", "CDG", "FRA", "AMS", "MAD", "FCO", "MUC", "ZUR", "VIE", "CPH",
+			"ARN", "HEL", "OSL", "LIS", "ATH", "IST", "SVO", "WAW", "PRG", "BUD",
+		},
+		"asia_pacific": {
+			"NRT", "ICN", "PVG", "PEK", "HKG", "SIN", "BKK", "KUL", "CGK", "MNL",
+			"TPE", "CAN", "DEL", "BOM", "SYD", "MEL", "DXB", "DOH", "KWI", "CAI",
+		},
+		"middle_east_africa": {
+			"DXB", "DOH", "AUH", "KWI", "CAI", "JNB", "CPT", "NBO", "ADD", "DAR",
+			"LOS", "ACC", "CAS", "TUN", "ALG", "RUH", "JED", "AMM", "BEY", "BAH",
+		},
+		"south_america": {
+			"GRU", "EZE", "SCL", "BOG", "LIM", "CWB", "FOR", "GIG", "BSB", "MAO",
+			"UIO", "MVD", "ASU", "CCS", "GEO", "PBM", "BEL", "STM", "CGB", "THE",
+		},
+	}
+}
+
+// defaultMajorHubs returns the built-in 2-stop hub candidates, most important
+// first
+func defaultMajorHubs() []string {
+	return []string{
+		"DXB", "DOH", "IST", "FRA", "LHR", "CDG", "AMS",
+		"SIN", "HKG", "ICN", "NRT", "PVG",
+		"JFK", "LAX", "ORD", "DFW", "ATL",
+	}
+}
+
+// defaultExploreDestinations returns popular leisure and business
+// destinations across every region
+func defaultExploreDestinations() []string {
+	return []string{
+		"LHR", "CDG", "AMS", "FCO", "BCN", "MAD", "IST", "ATH", "LIS", "PRG",
+		"DXB", "DOH", "CAI", "NBO", "CPT",
+		"BKK", "SIN", "HKG", "NRT", "ICN", "TPE", "KUL", "DPS", "DEL", "SYD",
+		"JFK", "LAX", "MIA", "YVR", "CUN", "MEX", "GRU", "EZE", "LIM",
+	}
 }
 
-func Load() (*Config, error) {
-	config := &Config{
-		Port:             getEnv("PORT", "8080"),
-		AmadeusAPIKey:    os.Getenv("AMADEUS_API_KEY"),
-		AmadeusAPISecret: os.Getenv("AMADEUS_API_SECRET"),
-		AmadeusBaseURL:   getEnv("AMADEUS_BASE_URL", "https://test.api.amadeus.com"),
-		Environment:      getEnv("ENVIRONMENT", "development"),
-		AllowedOrigins:   []string{"http://localhost:3000", "http://frontend:3000"},
+// Load builds the configuration from, in increasing order of precedence, the
+// built-in defaults, the YAML file named by --config or CONFIG_FILE,
+// environment variables and the command-line flags in args. Every invalid
+// value is reported in a *ValidationError, not just the first.
+func Load(args []string) (*Config, error) {
+	config := Defaults()
+	settings := config.settings()
+
+	fs := flag.NewFlagSet("cheapest-flight-backend", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML configuration file")
+	type override struct {
+		setting setting
+		value   string
+	}
+	var overrides []override
+	for _, s := range settings {
+		if s.secret {
+			continue
+		}
+		fs.Func(s.flagName(), "overrides "+s.env, func(value string) error {
+			overrides = append(overrides, override{s, value})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
 	}
 
-	// Validate required fields
-	if config.AmadeusAPIKey == "" {
-		return nil, fmt.Errorf("AMADEUS_API_KEY is required")
+	var problems []string
+	if *configFile != "" {
+		problems = append(problems, config.loadFile(*configFile)...)
 	}
-	if config.AmadeusAPISecret == "" {
-		return nil, fmt.Errorf("AMADEUS_API_SECRET is required")
+	for _, s := range settings {
+		value, err := lookupEnv(s)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if value != "" {
+			if err := s.set(value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", s.env, err))
+			}
+		}
+	}
+	for _, o := range overrides {
+		if err := o.setting.set(o.value); err != nil {
+			problems = append(problems, fmt.Sprintf("--%s: %v", o.setting.flagName(), err))
+		}
 	}
 
+	config.normalize()
+	problems = append(problems, config.validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	// validate has already reported a bad list
+	config.TrustedProxies, _ = utils.ParseTrustedProxies(config.TrustedProxyList)
 	return config, nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// lookupEnv returns the setting's environment variable or, for a secret,
+// the contents of the file named by its _FILE variant
+func lookupEnv(s setting) (string, error) {
+	value := os.Getenv(s.env)
+	if !s.secret {
+		return value, nil
+	}
+	path := os.Getenv(s.env + "_FILE")
+	if path == "" {
+		return value, nil
+	}
+	if value != "" {
+		return "", fmt.Errorf("set %s or %s_FILE, not both", s.env, s.env)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s_FILE: %v", s.env, err)
+	}
+	// Secret files usually end with a newline that isn't part of the secret
+	value = strings.TrimRight(string(data), "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("%s_FILE: %s is empty", s.env, path)
+	}
+	return value, nil
+}
+
+// loadFile decodes the YAML file at path over the current values. Each key
+// is decoded on its own so that every bad value is reported, and unknown keys
+// are errors so typos don't go unnoticed.
+func (c *Config) loadFile(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []string{fmt.Sprintf("config file: %v", err)}
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return []string{fmt.Sprintf("%s: %v", path, err)}
+	}
+	if len(root.Content) == 0 {
+		return nil // empty file
+	}
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return []string{fmt.Sprintf("%s: must be a mapping of settings", path)}
+	}
+
+	fields := make(map[string]reflect.Value)
+	value := reflect.ValueOf(c).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		if key := value.Type().Field(i).Tag.Get("yaml"); key != "-" {
+			fields[key] = value.Field(i)
+		}
+	}
+
+	var problems []string
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		key, node := doc.Content[i], doc.Content[i+1]
+		field, ok := fields[key.Value]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s:%d: unknown setting %q", path, key.Line, key.Value))
+			continue
+		}
+		// Decode into a fresh value so lists and maps replace the defaults
+		// rather than merging with them
+		decoded := reflect.New(field.Type())
+		if err := node.Decode(decoded.Interface()); err != nil {
+			problems = append(problems, fmt.Sprintf("%s:%d: %s: %s", path, node.Line, key.Value, yamlErrorMessage(err)))
+			continue
+		}
+		field.Set(decoded.Elem())
+	}
+	return problems
+}
+
+// yamlErrorMessage drops the "yaml: unmarshal errors" preamble and line
+// numbers that callers already report
+func yamlErrorMessage(err error) string {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		message := typeErr.Errors[0]
+		if _, rest, ok := strings.Cut(message, ": "); ok && strings.HasPrefix(message, "line ") {
+			message = rest
+		}
+		return message
+	}
+	return err.Error()
+}
+
+// normalize upper-cases codes so hubs compare equal to normalized searches
+func (c *Config) normalize() {
+	c.Currency = strings.ToUpper(strings.TrimSpace(c.Currency))
+	for region, codes := range c.HubAirports {
+		c.HubAirports[region] = upperAll(codes)
+	}
+	c.MajorHubs = upperAll(c.MajorHubs)
+	c.ExploreDestinations = upperAll(c.ExploreDestinations)
+}
+
+func upperAll(codes []string) []string {
+	upper := make([]string, len(codes))
+	for i, code := range codes {
+		upper[i] = utils.NormalizeAirportCode(code)
+	}
+	return upper
+}
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// validate checks the assembled configuration and returns every problem
+func (c *Config) validate() []string {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.AmadeusAPIKey != "" || c.AmadeusAPISecret != "" || len(c.AmadeusCredentials) > 0,
+		"AMADEUS_API_KEY and AMADEUS_API_SECRET, or AMADEUS_CREDENTIALS, are required")
+	check((c.AmadeusAPIKey == "") == (c.AmadeusAPISecret == ""), "AMADEUS_API_KEY and AMADEUS_API_SECRET must be set together")
+	for i, creds := range c.AmadeusCredentials {
+		check(creds.APIKey != "" && creds.APISecret != "", "AMADEUS_CREDENTIALS: set %d needs both a key and a secret", i+1)
+	}
+	check(c.AmadeusCredentialStrategy == "failover" || c.AmadeusCredentialStrategy == "rotate",
+		"AMADEUS_CREDENTIAL_STRATEGY must be failover or rotate")
+	baseURL, err := url.Parse(c.AmadeusBaseURL)
+	check(err == nil && baseURL.Scheme != "" && baseURL.Host != "", "AMADEUS_BASE_URL must be an absolute URL")
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		check(err == nil && u.Scheme != "" && u.Host != "" && (u.Path == "" || u.Path == "/"),
+			"ALLOWED_ORIGINS: %q must be * or a scheme and host such as https://example.com", origin)
+	}
+
+	check(c.ServerReadTimeout > 0, "SERVER_READ_TIMEOUT must be positive")
+	check(c.ServerWriteTimeout > 0, "SERVER_WRITE_TIMEOUT must be positive")
+	check(c.ServerIdleTimeout > 0, "SERVER_IDLE_TIMEOUT must be positive")
+	check(c.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+
+	check(c.AmadeusMaxConcurrency >= 1, "AMADEUS_MAX_CONCURRENCY must be at least 1")
+	check(c.AmadeusDailyQuota >= 0, "AMADEUS_DAILY_QUOTA cannot be negative")
+	check(c.AmadeusTimeout > 0, "AMADEUS_TIMEOUT must be positive")
+	check(currencyPattern.MatchString(c.Currency), "CURRENCY must be a three-letter code such as USD")
+
+	check(c.SearchTimeout > 0, "SEARCH_TIMEOUT must be positive")
+	check(len(c.HubAirports) > 0, "HUB_AIRPORTS must list at least one region")
+	regions := make([]string, 0, len(c.HubAirports))
+	for region := range c.HubAirports {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	for _, region := range regions {
+		check(len(c.HubAirports[region]) > 0, "HUB_AIRPORTS: region %q has no airports", region)
+		for _, code := range c.HubAirports[region] {
+			check(utils.ValidateAirportCode(code), "HUB_AIRPORTS: %q in region %q is not an airport code", code, region)
+		}
+	}
+	check(len(c.MajorHubs) > 0, "MAJOR_HUBS must list at least one airport")
+	for _, code := range c.MajorHubs {
+		check(utils.ValidateAirportCode(code), "MAJOR_HUBS: %q is not an airport code", code)
+	}
+	check(c.MaxRelevantHubs >= 1, "MAX_RELEVANT_HUBS must be at least 1")
+	check(c.MaxTwoStopHubs >= 2, "MAX_TWO_STOP_HUBS must be at least 2")
+	check(c.OneStopConcurrency >= 1, "ONE_STOP_CONCURRENCY must be at least 1")
+	check(c.TwoStopConcurrency >= 1, "TWO_STOP_CONCURRENCY must be at least 1")
+	check(c.TopResults >= 1, "TOP_RESULTS must be at least 1")
+	check(c.MaxNearbyAirports >= 0, "MAX_NEARBY_AIRPORTS cannot be negative")
+	check(len(c.ExploreDestinations) > 0, "EXPLORE_DESTINATIONS must list at least one airport")
+	for _, code := range c.ExploreDestinations {
+		check(utils.ValidateAirportCode(code), "EXPLORE_DESTINATIONS: %q is not an airport code", code)
+	}
+	check(c.ExploreSampleDates >= 1, "EXPLORE_SAMPLE_DATES must be at least 1")
+	check(c.ExploreConcurrency >= 1, "EXPLORE_CONCURRENCY must be at least 1")
+	check(c.ExploreMaxCalls >= 1, "EXPLORE_MAX_CALLS must be at least 1")
+
+	check(c.SearchJobWorkers >= 1, "SEARCH_JOB_WORKERS must be at least 1")
+	check(c.SearchJobQueue >= 0, "SEARCH_JOB_QUEUE cannot be negative")
+	check(c.WatchInterval >= time.Minute, "WATCH_INTERVAL must be at least 1m")
+	check(c.WatchBudgetReserve >= 0, "WATCH_BUDGET_RESERVE cannot be negative")
+	check(c.DatabaseDriver == "sqlite" || c.DatabaseDriver == "memory", "DATABASE_DRIVER must be sqlite or memory")
+	if c.AirportsFile != "" {
+		info, err := os.Stat(c.AirportsFile)
+		check(err == nil && !info.IsDir(), "AIRPORTS_FILE: %s is not a readable file", c.AirportsFile)
+	}
+	check(c.WebhookMaxAttempts >= 1, "WEBHOOK_MAX_ATTEMPTS must be at least 1")
+	check(c.JWTSecret != "" || c.Environment != "production", "JWT_SECRET is required in production")
+	check(c.JWTSecret == "" || len(c.JWTSecret) >= 32, "JWT_SECRET must be at least 32 characters")
+	check(c.JWTTTL >= time.Minute, "JWT_TTL must be at least 1m")
+	check(c.APIKeyRatePerMinute >= 1, "API_KEY_RATE_PER_MINUTE must be at least 1")
+	check(c.APIKeyDailySearches >= 1, "API_KEY_DAILY_SEARCHES must be at least 1")
+	check(c.RateLimitPerMinute >= 0, "RATE_LIMIT_PER_MINUTE cannot be negative")
+	check(c.RateLimitSearchCost >= 1, "RATE_LIMIT_SEARCH_COST must be at least 1")
+	check(c.RateLimitBurst >= c.RateLimitSearchCost, "RATE_LIMIT_BURST must be at least RATE_LIMIT_SEARCH_COST")
+
+	if _, err := utils.ParseTrustedProxies(c.TrustedProxyList); err != nil {
+		problems = append(problems, fmt.Sprintf("TRUSTED_PROXIES: %v", err))
+	}
+
+	switch c.TracingExporter {
+	case "none", "stdout", "otlp":
+	default:
+		problems = append(problems, "TRACING_EXPORTER must be none, stdout or otlp")
+	}
+	check(c.TracingSampleRatio >= 0 && c.TracingSampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+
+	return problems
+}
+
+// RestartRequired returns the yaml keys of settings that differ in next but
+// only take effect after a restart
+func (c *Config) RestartRequired(next *Config) []string {
+	var changed []string
+	current, updated := reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < current.NumField(); i++ {
+		key := current.Type().Field(i).Tag.Get("yaml")
+		if key == "-" || reloadable[key] {
+			continue
+		}
+		if !reflect.DeepEqual(current.Field(i).Interface(), updated.Field(i).Interface()) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// setting binds a config field to its environment variable. Every setting
+// can also be given as a flag named after the variable, such as
+// --amadeus-max-concurrency, except secrets, which would show up in process
+// listings.
+type setting struct {
+	env    string
+	secret bool
+	set    func(value string) error
+}
+
+func (s setting) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(s.env), "_", "-")
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{env: "PORT", set: stringVar(&c.Port)},
+		{env: "AMADEUS_API_KEY", secret: true, set: stringVar(&c.AmadeusAPIKey)},
+		{env: "AMADEUS_API_SECRET", secret: true, set: stringVar(&c.AmadeusAPISecret)},
+		{env: "AMADEUS_BASE_URL", set: stringVar(&c.AmadeusBaseURL)},
+		{env: "AMADEUS_CREDENTIALS", secret: true, set: credentialsVar(&c.AmadeusCredentials)},
+		{env: "AMADEUS_CREDENTIAL_STRATEGY", set: stringVar(&c.AmadeusCredentialStrategy)},
+		{env: "ENVIRONMENT", set: stringVar(&c.Environment)},
+		{env: "ALLOWED_ORIGINS", set: listVar(&c.AllowedOrigins)},
+		{env: "SERVER_READ_TIMEOUT", set: durationVar(&c.ServerReadTimeout)},
+		{env: "SERVER_WRITE_TIMEOUT", set: durationVar(&c.ServerWriteTimeout)},
+		{env: "SERVER_IDLE_TIMEOUT", set: durationVar(&c.ServerIdleTimeout)},
+		{env: "SHUTDOWN_TIMEOUT", set: durationVar(&c.ShutdownTimeout)},
+		{env: "AMADEUS_MAX_CONCURRENCY", set: intVar(&c.AmadeusMaxConcurrency)},
+		{env: "AMADEUS_DAILY_QUOTA", set: intVar(&c.AmadeusDailyQuota)},
+		{env: "AMADEUS_TIMEOUT", set: durationVar(&c.AmadeusTimeout)},
+		{env: "CURRENCY", set: stringVar(&c.Currency)},
+		{env: "SEARCH_TIMEOUT", set: durationVar(&c.SearchTimeout)},
+		{env: "HUB_AIRPORTS", set: hubsVar(&c.HubAirports)},
+		{env: "MAJOR_HUBS", set: listVar(&c.MajorHubs)},
+		{env: "MAX_RELEVANT_HUBS", set: intVar(&c.MaxRelevantHubs)},
+		{env: "MAX_TWO_STOP_HUBS", set: intVar(&c.MaxTwoStopHubs)},
+		{env: "ONE_STOP_CONCURRENCY", set: intVar(&c.OneStopConcurrency)},
+		{env: "TWO_STOP_CONCURRENCY", set: intVar(&c.TwoStopConcurrency)},
+		{env: "TOP_RESULTS", set: intVar(&c.TopResults)},
+		{env: "MAX_NEARBY_AIRPORTS", set: intVar(&c.MaxNearbyAirports)},
+		{env: "EXPLORE_DESTINATIONS", set: listVar(&c.ExploreDestinations)},
+		{env: "EXPLORE_SAMPLE_DATES", set: intVar(&c.ExploreSampleDates)},
+		{env: "EXPLORE_CONCURRENCY", set: intVar(&c.ExploreConcurrency)},
+		{env: "EXPLORE_MAX_CALLS", set: intVar(&c.ExploreMaxCalls)},
+		{env: "SEARCH_JOB_WORKERS", set: intVar(&c.SearchJobWorkers)},
+		{env: "SEARCH_JOB_QUEUE", set: intVar(&c.SearchJobQueue)},
+		{env: "SEARCH_JOB_TIMEOUT", set: durationVar(&c.SearchJobTimeout)},
+		{env: "SEARCH_JOB_TTL", set: durationVar(&c.SearchJobTTL)},
+		{env: "DATABASE_DRIVER", set: stringVar(&c.DatabaseDriver)},
+		{env: "DATABASE_PATH", set: stringVar(&c.DatabasePath)},
+		{env: "AIRPORTS_FILE", set: stringVar(&c.AirportsFile)},
+		{env: "WATCH_INTERVAL", set: durationVar(&c.WatchInterval)},
+		{env: "WATCH_BUDGET_RESERVE", set: intVar(&c.WatchBudgetReserve)},
+		{env: "WEBHOOK_MAX_ATTEMPTS", set: intVar(&c.WebhookMaxAttempts)},
+		{env: "WEBHOOK_INITIAL_BACKOFF", set: durationVar(&c.WebhookInitialBackoff)},
+		{env: "WEBHOOK_TIMEOUT", set: durationVar(&c.WebhookTimeout)},
+		{env: "WEBHOOK_ALLOW_PRIVATE_DESTINATIONS", set: boolVar(&c.WebhookAllowPrivateDestinations)},
+		{env: "JWT_SECRET", secret: true, set: stringVar(&c.JWTSecret)},
+		{env: "JWT_TTL", set: durationVar(&c.JWTTTL)},
+		{env: "ADMIN_TOKEN", secret: true, set: stringVar(&c.AdminToken)},
+		{env: "API_KEY_RATE_PER_MINUTE", set: intVar(&c.APIKeyRatePerMinute)},
+		{env: "API_KEY_DAILY_SEARCHES", set: intVar(&c.APIKeyDailySearches)},
+		{env: "RATE_LIMIT_PER_MINUTE", set: intVar(&c.RateLimitPerMinute)},
+		{env: "RATE_LIMIT_BURST", set: intVar(&c.RateLimitBurst)},
+		{env: "RATE_LIMIT_SEARCH_COST", set: intVar(&c.RateLimitSearchCost)},
+		{env: "TRUSTED_PROXIES", set: listVar(&c.TrustedProxyList)},
+		{env: "LOG_LEVEL", set: func(value string) (err error) {
+			c.LogLevel, err = logging.ParseLevel(value)
+			return err
+		}},
+		{env: "TRACING_EXPORTER", set: stringVar(&c.TracingExporter)},
+		{env: "TRACING_SAMPLE_RATIO", set: floatVar(&c.TracingSampleRatio)},
+	}
+}
+
+func stringVar(p *string) func(string) error {
+	return func(value string) error {
+		*p = value
+		return nil
+	}
+}
+
+func intVar(p *int) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("must be an integer, got %q", value)
+		}
+		*p = n
+		return nil
+	}
+}
+
+func boolVar(p *bool) func(string) error {
+	return func(value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be true or false, got %q", value)
+		}
+		*p = b
+		return nil
+	}
+}
+
+func floatVar(p *float64) func(string) error {
+	return func(value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number, got %q", value)
+		}
+		*p = f
+		return nil
+	}
+}
+
+func durationVar(p *time.Duration) func(string) error {
+	return func(value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("must be a duration such as 30s or 5m, got %q", value)
+		}
+		*p = d
+		return nil
+	}
+}
+
+// listVar parses a comma-separated list
+func listVar(p *[]string) func(string) error {
+	return func(value string) error {
+		*p = splitList(value, ",")
+		return nil
+	}
+}
+
+// hubsVar parses hubs by region, written "europe=LHR,CDG;asia_pacific=SIN,HKG"
+func hubsVar(p *map[string][]string) func(string) error {
+	return func(value string) error {
+		hubs := make(map[string][]string)
+		for _, entry := range splitList(value, ";") {
+			region, codes, ok := strings.Cut(entry, "=")
+			if region = strings.TrimSpace(region); !ok || region == "" {
+				return fmt.Errorf("%q must look like region=AAA,BBB", entry)
+			}
+			hubs[region] = splitList(codes, ",")
+		}
+		*p = hubs
+		return nil
+	}
+}
+
+// credentialsVar parses "key:secret" pairs separated by commas or newlines,
+// so a secret file can hold one pair per line
+func credentialsVar(p *[]Credentials) func(string) error {
+	return func(value string) error {
+		var sets []Credentials
+		for _, pair := range splitList(strings.ReplaceAll(value, "\n", ","), ",") {
+			key, secret, ok := strings.Cut(pair, ":")
+			if !ok {
+				return fmt.Errorf("set %d must look like key:secret", len(sets)+1)
+			}
+			sets = append(sets, Credentials{APIKey: strings.TrimSpace(key), APISecret: strings.TrimSpace(secret)})
+		}
+		*p = sets
+		return nil
+	}
+}
+
+// splitList splits value on sep, trimming entries and dropping empty ones
+func splitList(value, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
-	return defaultValue
+	return items
 }
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/utils"
+)
+
+// Page sizes for airport searches and listings
+const (
+	defaultAirportSearchLimit = 20
+	defaultAirportListLimit   = 50
+	maxAirportPageLimit       = 100
+)
+
+// Defaults and limits for nearest airport lookups
+const (
+	defaultNearestRadiusKm = 100
+	maxNearestRadiusKm     = 500
+	defaultNearestLimit    = 10
+)
+
+type AirportHandler struct {
+	airportService *services.AirportService
+}
+
+func NewAirportHandler(airportService *services.AirportService) *AirportHandler {
+	return &AirportHandler{airportService: airportService}
+}
+
+// ListAirports searches airports when given a q parameter and lists them in
+// IATA code order otherwise. Both can be narrowed to a country and region.
+// Searches are paged with offset, listings with the opaque nextCursor of the
+// previous page.
+func (h *AirportHandler) ListAirports(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := params.Get("q")
+
+	filter := services.AirportFilter{
+		CountryCode: strings.ToUpper(strings.TrimSpace(params.Get("country"))),
+		Region:      strings.TrimSpace(params.Get("region")),
+	}
+	if filter.CountryCode != "" && !isCountryCode(filter.CountryCode) {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "country must be a two-letter country code")
+		return
+	}
+
+	limit := defaultAirportListLimit
+	if query != "" {
+		limit = defaultAirportSearchLimit
+	}
+	if l := params.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 || n > maxAirportPageLimit {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxAirportPageLimit))
+			return
+		}
+		limit = n
+	}
+
+	if query != "" {
+		if params.Has("cursor") {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "cursor cannot be used with q; page searches with offset")
+			return
+		}
+		offset := 0
+		if o := params.Get("offset"); o != "" {
+			n, err := strconv.Atoi(o)
+			if err != nil || n < 0 {
+				utils.WriteErrorResponse(w, http.StatusBadRequest, "offset must be a number of 0 or more")
+				return
+			}
+			offset = n
+		}
+		airports, total := h.airportService.SearchAirports(query, filter, limit, offset)
+		utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+			"airports": airports,
+			"total":    total,
+			"query":    query,
+			"limit":    limit,
+			"offset":   offset,
+		})
+		return
+	}
+
+	if params.Has("offset") {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "offset can only be used with q; page the listing with cursor")
+		return
+	}
+	after := ""
+	if c := params.Get("cursor"); c != "" {
+		code, err := decodeAirportCursor(c)
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+		after = code
+	}
+
+	airports, total, more := h.airportService.ListAirports(filter, after, limit)
+	response := map[string]interface{}{
+		"airports": airports,
+		"total":    total,
+		"query":    query,
+		"limit":    limit,
+	}
+	if more {
+		response["nextCursor"] = encodeAirportCursor(airports[len(airports)-1].IATA)
+	}
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// GetAirport returns one airport by its IATA or ICAO code
+func (h *AirportHandler) GetAirport(w http.ResponseWriter, r *http.Request) {
+	airport, ok := h.airportService.LookupAirport(mux.Vars(r)["code"])
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusNotFound, "Airport not found")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, airport)
+}
+
+// NearestAirports returns the airports closest to a point, such as the
+// browser's location, nearest first
+func (h *AirportHandler) NearestAirports(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	lat, err := strconv.ParseFloat(params.Get("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "lat must be a latitude between -90 and 90")
+		return
+	}
+	lon, err := strconv.ParseFloat(params.Get("lon"), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "lon must be a longitude between -180 and 180")
+		return
+	}
+
+	radius := float64(defaultNearestRadiusKm)
+	if v := params.Get("radius"); v != "" {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n <= 0 || n > maxNearestRadiusKm {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("radius must be more than 0 and at most %d km", maxNearestRadiusKm))
+			return
+		}
+		radius = n
+	}
+
+	limit := defaultNearestLimit
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAirportPageLimit {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxAirportPageLimit))
+			return
+		}
+		limit = n
+	}
+
+	airports := h.airportService.NearestAirports(lat, lon, radius, limit)
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"airports": airports,
+		"total":    len(airports),
+		"lat":      lat,
+		"lon":      lon,
+		"radius":   radius,
+		"limit":    limit,
+	})
+}
+
+// ListCountries returns the countries that have airports, with how many each has
+func (h *AirportHandler) ListCountries(w http.ResponseWriter, r *http.Request) {
+	countries := h.airportService.Countries()
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"countries": countries,
+		"total":     len(countries),
+	})
+}
+
+// ReloadAirports rereads the airport data and swaps it in. Requests already
+// running finish with the data they started with; if the new data can't be
+// used, the old data stays.
+func (h *AirportHandler) ReloadAirports(w http.ResponseWriter, r *http.Request) {
+	previous := h.airportService.LoadReport()
+	report, err := h.airportService.Reload()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "airport data reload failed", "error", err)
+		utils.WriteErrorResponse(w, http.StatusUnprocessableEntity, "Failed to reload airports: "+err.Error())
+		return
+	}
+
+	LogAirportLoad(r.Context(), report)
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"report":           report,
+		"previousAirports": previous.Airports,
+	})
+}
+
+// LogAirportLoad logs a summary of loaded airport data and each row that was
+// rejected
+func LogAirportLoad(ctx context.Context, report services.AirportLoadReport) {
+	for _, rowErr := range report.Errors {
+		slog.WarnContext(ctx, "rejected airport data row", "source", report.Source, "line", rowErr.Line, "error", rowErr.Message)
+	}
+	slog.InfoContext(ctx, "airport data loaded",
+		"source", report.Source,
+		"airports", report.Airports,
+		"skipped", report.Skipped,
+		"rejected", report.Rejected,
+	)
+}
+
+// A listing cursor is the IATA code of the last airport on the previous page.
+// It is encoded so that clients treat it as opaque.
+func encodeAirportCursor(code string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(code))
+}
+
+func decodeAirportCursor(cursor string) (string, error) {
+	code, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", err
+	}
+	if !utils.ValidateAirportCode(string(code)) {
+		return "", fmt.Errorf("invalid airport code in cursor")
+	}
+	return string(code), nil
+}
+
+func isCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
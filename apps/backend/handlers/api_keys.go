@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/utils"
+)
+
+type APIKeyHandler struct {
+	keys *services.APIKeyService
+}
+
+func NewAPIKeyHandler(keys *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+// CreateKey issues an API key. The key is only returned in this response.
+func (h *APIKeyHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+
+	var req models.APIKeyRequest
+	if err := utils.ParseJSONRequest(r, &req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	req.Name = utils.SanitizeString(req.Name)
+	switch {
+	case req.Name == "":
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "name is required")
+		return
+	case req.RequestsPerMinute < 0:
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "requestsPerMinute cannot be negative")
+		return
+	case req.DailySearches < 0:
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "dailySearches cannot be negative")
+		return
+	}
+
+	issued, err := h.keys.Issue(r.Context(), req)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to issue API key", "error", err)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to issue API key")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "issued API key", "key_id", issued.ID, "prefix", issued.Prefix, "name", issued.Name)
+	w.Header().Set("Location", "/api/admin/keys/"+issued.ID)
+	utils.WriteJSONResponse(w, http.StatusCreated, issued)
+}
+
+// ListKeys returns every key with its current usage
+func (h *APIKeyHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.keys.List(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to list API keys", "error", err)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list API keys")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"keys":  keys,
+		"total": len(keys),
+	})
+}
+
+// RevokeKey disables a key; it stays listed with its revocation time
+func (h *APIKeyHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+
+	key, err := h.keys.Revoke(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeStoreError(w, r, err, "API key")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "revoked API key", "key_id", key.ID, "prefix", key.Prefix)
+	utils.WriteJSONResponse(w, http.StatusOK, key)
+}
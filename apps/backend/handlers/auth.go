@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/mail"
+
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/utils"
+)
+
+// Password length limits; bcrypt ignores anything past 72 bytes
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+type AuthHandler struct {
+	auth *services.AuthService
+}
+
+func NewAuthHandler(auth *services.AuthService) *AuthHandler {
+	return &AuthHandler{auth: auth}
+}
+
+// Register creates an account and signs the new user in
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+
+	var req models.CredentialsRequest
+	if err := utils.ParseJSONRequest(r, &req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if validationErrors := validateCredentials(req); len(validationErrors) > 0 {
+		utils.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Validation failed",
+			Message: "Request validation failed: " + validationErrors[0],
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	response, err := h.auth.Register(r.Context(), req.Email, req.Password)
+	if err != nil {
+		if errors.Is(err, services.ErrEmailTaken) {
+			utils.WriteErrorResponse(w, http.StatusConflict, "An account with this email already exists")
+			return
+		}
+		slog.ErrorContext(r.Context(), "failed to register user", "error", err)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create account")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "registered user", "user_id", response.User.ID)
+	utils.WriteJSONResponse(w, http.StatusCreated, response)
+}
+
+// Login exchanges an email and password for a token
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+
+	var req models.CredentialsRequest
+	if err := utils.ParseJSONRequest(r, &req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	response, err := h.auth.Login(r.Context(), req.Email, req.Password)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			utils.WriteErrorResponse(w, http.StatusUnauthorized, "Invalid email or password")
+			return
+		}
+		slog.ErrorContext(r.Context(), "failed to log in", "error", err)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+func validateCredentials(req models.CredentialsRequest) []string {
+	var errors []string
+
+	if address, err := mail.ParseAddress(req.Email); err != nil || address.Address != req.Email {
+		errors = append(errors, "email must be a valid email address")
+	}
+	if len(req.Password) < minPasswordLength {
+		errors = append(errors, "password must be at least 8 characters")
+	}
+	if len(req.Password) > maxPasswordLength {
+		errors = append(errors, "password must be at most 72 bytes")
+	}
+
+	return errors
+}
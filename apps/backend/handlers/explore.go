@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/utils"
+)
+
+const (
+	defaultExploreLimit  = 20
+	maxExploreLimit      = 100
+	maxExploreWindowDays = 60
+)
+
+type ExploreHandler struct {
+	explore        *services.ExploreService
+	amadeusService *services.AmadeusService
+	airportService *services.AirportService
+	searchTimeout  time.Duration
+}
+
+func NewExploreHandler(explore *services.ExploreService, amadeusService *services.AmadeusService, airportService *services.AirportService, searchTimeout time.Duration) *ExploreHandler {
+	return &ExploreHandler{
+		explore:        explore,
+		amadeusService: amadeusService,
+		airportService: airportService,
+		searchTimeout:  searchTimeout,
+	}
+}
+
+// Explore finds the cheapest destinations from an origin within a budget
+func (h *ExploreHandler) Explore(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+
+	var req models.ExploreRequest
+	if err := utils.ParseJSONRequest(r, &req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if validationErrors := validateExploreRequest(h.airportService, &req); len(validationErrors) > 0 {
+		response := models.ErrorResponse{
+			Error:   "Validation failed",
+			Message: "Request validation failed: " + validationErrors[0],
+			Code:    http.StatusBadRequest,
+		}
+		utils.WriteJSONResponse(w, http.StatusBadRequest, response)
+		return
+	}
+
+	// Like searches, finish what has been paid for even if the client leaves
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), h.searchTimeout)
+	defer cancel()
+	ctx, stats := services.WithSearchStats(ctx)
+
+	slog.InfoContext(r.Context(), "exploring destinations",
+		"origin", req.Origin,
+		"departure_from", req.DepartureFrom,
+		"departure_to", req.DepartureTo,
+		"max_price", req.MaxPrice,
+	)
+
+	destinations, source, err := h.explore.Explore(ctx, req)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "explore failed", "source", source, "error", err)
+		if errors.Is(err, services.ErrQuotaExhausted) {
+			writeQuotaExhausted(w, h.amadeusService.Budget())
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Explore failed: "+err.Error())
+		return
+	}
+
+	response := models.ExploreResponse{
+		Destinations:  destinations,
+		Total:         len(destinations),
+		Query:         req,
+		Source:        source,
+		UpstreamCalls: stats.Calls(),
+		Degraded:      stats.Rejected() > 0,
+		Message:       exploreMessage(destinations, req),
+	}
+	if response.Degraded {
+		response.Message += " (partial results: daily search quota reached)"
+	}
+
+	slog.InfoContext(r.Context(), "explore completed",
+		"origin", req.Origin,
+		"source", source,
+		"destinations", len(destinations),
+		"upstream_calls", stats.Calls(),
+	)
+	utils.WriteJSONResponse(w, http.StatusOK, response)
+}
+
+// validateExploreRequest normalizes the request, applies its defaults and
+// returns every problem with it
+func validateExploreRequest(airportService *services.AirportService, req *models.ExploreRequest) []string {
+	var errors []string
+
+	req.Origin = utils.NormalizeAirportCode(req.Origin)
+	if !airportService.ValidateAirportCode(req.Origin) {
+		errors = append(errors, "invalid origin airport code: "+req.Origin)
+	}
+
+	if req.DepartureTo == "" {
+		req.DepartureTo = req.DepartureFrom
+	}
+	fromErr := utils.ValidateDateString(req.DepartureFrom)
+	if fromErr != nil {
+		errors = append(errors, "departureFrom must be in YYYY-MM-DD format and not in the past")
+	}
+	toErr := utils.ValidateDateString(req.DepartureTo)
+	if toErr != nil {
+		errors = append(errors, "departureTo must be in YYYY-MM-DD format and not in the past")
+	}
+	if fromErr == nil && toErr == nil {
+		from, _ := time.Parse("2006-01-02", req.DepartureFrom)
+		to, _ := time.Parse("2006-01-02", req.DepartureTo)
+		if to.Before(from) {
+			errors = append(errors, "departureTo cannot be before departureFrom")
+		} else if to.Sub(from) > maxExploreWindowDays*24*time.Hour {
+			errors = append(errors, fmt.Sprintf("the departure window cannot be longer than %d days", maxExploreWindowDays))
+		}
+	}
+
+	if req.MaxPrice <= 0 {
+		errors = append(errors, "maxPrice must be greater than 0")
+	}
+
+	if req.Limit == 0 {
+		req.Limit = defaultExploreLimit
+	}
+	if req.Limit < 1 || req.Limit > maxExploreLimit {
+		errors = append(errors, fmt.Sprintf("limit must be between 1 and %d", maxExploreLimit))
+	}
+
+	return errors
+}
+
+func exploreMessage(destinations []models.ExploreDestination, req models.ExploreRequest) string {
+	if len(destinations) == 0 {
+		return fmt.Sprintf("No destinations found from %s within your budget", req.Origin)
+	}
+	cheapest := destinations[0]
+	return fmt.Sprintf("Found %d destinations from %s, from %.0f %s to %s",
+		len(destinations), req.Origin, cheapest.Price, cheapest.Currency, cheapest.Destination)
+}
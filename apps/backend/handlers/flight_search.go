@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"cheapest-flight-backend/models"
@@ -16,13 +18,15 @@ type FlightSearchHandler struct {
 	routeOptimizer *services.RouteOptimizer
 	amadeusService *services.AmadeusService
 	airportService *services.AirportService
+	searchTimeout  time.Duration
 }
 
-func NewFlightSearchHandler(routeOptimizer *services.RouteOptimizer, amadeusService *services.AmadeusService, airportService *services.AirportService) *FlightSearchHandler {
+func NewFlightSearchHandler(routeOptimizer *services.RouteOptimizer, amadeusService *services.AmadeusService, airportService *services.AirportService, searchTimeout time.Duration) *FlightSearchHandler {
 	return &FlightSearchHandler{
 		routeOptimizer: routeOptimizer,
 		amadeusService: amadeusService,
 		airportService: airportService,
+		searchTimeout:  searchTimeout,
 	}
 }
 
@@ -35,6 +39,15 @@ func (h *FlightSearchHandler) SearchFlights(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	explain := false
+	if value := r.URL.Query().Get("explain"); value != "" {
+		var err error
+		if explain, err = strconv.ParseBool(value); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "explain must be true or false")
+			return
+		}
+	}
+
 	var req models.FlightSearchRequest
 	if err := utils.ParseJSONRequest(r, &req); err != nil {
 		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
@@ -42,7 +55,7 @@ func (h *FlightSearchHandler) SearchFlights(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Enhanced validation with airport service
-	if validationErrors := h.validateFlightSearchRequest(&req); len(validationErrors) > 0 {
+	if validationErrors := validateFlightSearchRequest(h.airportService, &req); len(validationErrors) > 0 {
 		response := models.ErrorResponse{
 			Error:   "Validation failed",
 			Message: "Request validation failed: " + validationErrors[0],
@@ -52,53 +65,87 @@ func (h *FlightSearchHandler) SearchFlights(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	// Keep the request's values (such as its ID) but not its cancellation, so
+	// a search already paid for still completes if the client goes away
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), h.searchTimeout)
 	defer cancel()
+	ctx, stats := services.WithSearchStats(ctx)
+	var diag *services.SearchDiagnostics
+	if explain {
+		ctx, diag = services.WithSearchDiagnostics(ctx)
+	}
 
-	log.Printf("Searching cheapest flights: %s -> %s on %s for %d passengers",
-		req.Origin, req.Destination, req.Date, req.Passengers)
+	slog.InfoContext(r.Context(), "searching cheapest flights",
+		"origin", req.Origin,
+		"destination", req.Destination,
+		"date", req.Date,
+		"passengers", req.Passengers,
+	)
 
 	// Search for flights using the route optimizer
 	flights, err := h.routeOptimizer.OptimizeRoutes(ctx, req)
 	if err != nil {
-		log.Printf("Flight search error: %v", err)
+		slog.ErrorContext(r.Context(), "flight search failed", "error", err)
+		if errors.Is(err, services.ErrQuotaExhausted) {
+			writeQuotaExhausted(w, h.amadeusService.Budget())
+			return
+		}
 		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Flight search failed: "+err.Error())
 		return
 	}
 
 	// Create response with route and airline info only
 	response := models.FlightSearchResponse{
-		Flights: flights,
-		Total:   len(flights),
-		Query:   req,
-		Message: h.generateResponseMessage(flights),
-	}
-
-	log.Printf("Found %d flight options for %s -> %s", len(flights), req.Origin, req.Destination)
+		Flights:       flights,
+		Total:         len(flights),
+		Query:         req,
+		Message:       generateResponseMessage(flights),
+		UpstreamCalls: stats.Calls(),
+		Degraded:      stats.Rejected() > 0,
+		Diagnostics:   diag.Report(),
+	}
+	if response.Degraded {
+		response.Message += " (partial results: daily search quota reached)"
+	}
+
+	slog.InfoContext(r.Context(), "flight search completed",
+		"origin", req.Origin,
+		"destination", req.Destination,
+		"flights", len(flights),
+		"upstream_calls", stats.Calls(),
+	)
 	utils.WriteJSONResponse(w, http.StatusOK, response)
 }
 
+// writeQuotaExhausted tells the client to retry once the daily quota resets
+func writeQuotaExhausted(w http.ResponseWriter, budget *services.CallBudget) {
+	retryAfter := time.Until(budget.ResetsAt())
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+	utils.WriteErrorResponse(w, http.StatusServiceUnavailable, "Daily flight search quota exhausted, please try again later")
+}
+
 // Enhanced validation using airport service
-func (h *FlightSearchHandler) validateFlightSearchRequest(req *models.FlightSearchRequest) []string {
+func validateFlightSearchRequest(airportService *services.AirportService, req *models.FlightSearchRequest) []string {
 	var errors []string
 
 	// Normalize airport codes
 	req.Origin = utils.NormalizeAirportCode(req.Origin)
 	req.Destination = utils.NormalizeAirportCode(req.Destination)
 
-	// Validate origin airport
-	if !h.airportService.ValidateAirportCode(req.Origin) {
-		errors = append(errors, "invalid origin airport code: "+req.Origin)
+	// Validate origin and destination, which may be airports or city codes
+	// such as LON
+	if !airportService.ValidateLocationCode(req.Origin) {
+		errors = append(errors, "invalid origin airport or city code: "+req.Origin)
 	}
-
-	// Validate destination airport
-	if !h.airportService.ValidateAirportCode(req.Destination) {
-		errors = append(errors, "invalid destination airport code: "+req.Destination)
+	if !airportService.ValidateLocationCode(req.Destination) {
+		errors = append(errors, "invalid destination airport or city code: "+req.Destination)
 	}
 
-	// Check if origin and destination are different
+	// Check if origin and destination are different, including LON and LHR
 	if req.Origin == req.Destination {
 		errors = append(errors, "origin and destination cannot be the same")
+	} else if sharedAirport(airportService, req.Origin, req.Destination) {
+		errors = append(errors, "origin and destination cannot share an airport")
 	}
 
 	// Validate date
@@ -111,10 +158,26 @@ func (h *FlightSearchHandler) validateFlightSearchRequest(req *models.FlightSear
 		errors = append(errors, "passenger count must be between 1 and 9")
 	}
 
+	if req.NearbyRadiusKm < 0 || req.NearbyRadiusKm > models.MaxNearbyRadiusKm {
+		errors = append(errors, fmt.Sprintf("nearbyRadiusKm must be between 0 and %d", models.MaxNearbyRadiusKm))
+	}
+
 	return errors
 }
 
-func (h *FlightSearchHandler) generateResponseMessage(flights []models.Flight) string {
+// sharedAirport reports whether two location codes cover a common airport
+func sharedAirport(airportService *services.AirportService, origin, destination string) bool {
+	for _, a := range airportService.LocationAirports(origin) {
+		for _, b := range airportService.LocationAirports(destination) {
+			if a == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func generateResponseMessage(flights []models.Flight) string {
 	if len(flights) == 0 {
 		return "No flights found for your search criteria"
 	}
@@ -165,7 +228,7 @@ func (h *FlightSearchHandler) HealthCheck(w http.ResponseWriter, r *http.Request
 	}
 
 	var amadeusStatus string
-	if err := h.amadeusService.HealthCheck(); err != nil {
+	if err := h.amadeusService.HealthCheck(r.Context()); err != nil {
 		amadeusStatus = "unhealthy: " + err.Error()
 	} else {
 		amadeusStatus = "healthy"
@@ -175,38 +238,9 @@ func (h *FlightSearchHandler) HealthCheck(w http.ResponseWriter, r *http.Request
 		"status":          "healthy",
 		"service":         "flight-search",
 		"amadeus_service": amadeusStatus,
-		"airports_loaded": len(h.airportService.GetAllAirports()),
+		"airports_loaded": h.airportService.LoadReport().Airports,
 		"timestamp":       time.Now().UTC().Format(time.RFC3339),
 	}
 
 	utils.WriteJSONResponse(w, http.StatusOK, response)
 }
-
-// GetSupportedAirports returns airport information from CSV
-func (h *FlightSearchHandler) GetSupportedAirports(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		utils.WriteErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
-		return
-	}
-
-	query := r.URL.Query().Get("q")
-	var airports []services.Airport
-
-	if query != "" {
-		airports = h.airportService.SearchAirports(query)
-	} else {
-		airports = h.airportService.GetAllAirports()
-		// Limit to first 50 for performance
-		if len(airports) > 50 {
-			airports = airports[:50]
-		}
-	}
-
-	response := map[string]interface{}{
-		"airports": airports,
-		"total":    len(airports),
-		"query":    query,
-	}
-
-	utils.WriteJSONResponse(w, http.StatusOK, response)
-}
@@ -5,18 +5,21 @@ import (
 	"runtime"
 	"time"
 
+	"cheapest-flight-backend/services"
 	"cheapest-flight-backend/utils"
 )
 
 type HealthHandler struct {
 	StartTime time.Time
 	Version   string
+	budget    *services.CallBudget
 }
 
-func NewHealthHandler(version string) *HealthHandler {
+func NewHealthHandler(version string, budget *services.CallBudget) *HealthHandler {
 	return &HealthHandler{
 		StartTime: time.Now(),
 		Version:   version,
+		budget:    budget,
 	}
 }
 
@@ -40,7 +43,8 @@ func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 			"memory_mb":  getMemoryUsageMB(),
 			"go_version": runtime.Version(),
 		},
-		"dependencies": h.checkDependencies(),
+		"dependencies":  h.checkDependencies(),
+		"amadeus_quota": h.budget.Usage(),
 	}
 
 	utils.WriteJSONResponse(w, http.StatusOK, response)
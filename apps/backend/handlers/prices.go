@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/utils"
+)
+
+type PriceHandler struct {
+	priceHistory   *services.PriceHistoryService
+	airportService *services.AirportService
+}
+
+func NewPriceHandler(priceHistory *services.PriceHistoryService, airportService *services.AirportService) *PriceHandler {
+	return &PriceHandler{
+		priceHistory:   priceHistory,
+		airportService: airportService,
+	}
+}
+
+// GetPriceHistory returns the observed price trend for a route and travel date
+func (h *PriceHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	origin := utils.NormalizeAirportCode(query.Get("origin"))
+	destination := utils.NormalizeAirportCode(query.Get("destination"))
+	date := query.Get("date")
+
+	if !h.airportService.ValidateAirportCode(origin) {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "invalid origin airport code: "+origin)
+		return
+	}
+	if !h.airportService.ValidateAirportCode(destination) {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "invalid destination airport code: "+destination)
+		return
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "date must be in YYYY-MM-DD format")
+		return
+	}
+
+	history, err := h.priceHistory.History(r.Context(), origin, destination, date)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to load price history", "error", err)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load price history")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, history)
+}
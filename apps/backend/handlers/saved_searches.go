@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"cheapest-flight-backend/middleware"
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/store"
+	"cheapest-flight-backend/utils"
+)
+
+// maxSavedSearchName caps the optional label on a saved search
+const maxSavedSearchName = 100
+
+type SavedSearchHandler struct {
+	store          store.UserStore
+	airportService *services.AirportService
+}
+
+func NewSavedSearchHandler(userStore store.UserStore, airportService *services.AirportService) *SavedSearchHandler {
+	return &SavedSearchHandler{
+		store:          userStore,
+		airportService: airportService,
+	}
+}
+
+// ListSearches returns the signed-in user's saved searches, newest first
+func (h *SavedSearchHandler) ListSearches(w http.ResponseWriter, r *http.Request) {
+	user, _ := middleware.UserFromContext(r.Context())
+
+	searches, err := h.store.ListSavedSearches(r.Context(), user.ID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to list saved searches", "user_id", user.ID, "error", err)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list saved searches")
+		return
+	}
+	if searches == nil {
+		searches = []models.SavedSearch{}
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"searches": searches,
+		"total":    len(searches),
+	})
+}
+
+// SaveSearch stores a flight search on the signed-in user's account
+func (h *SavedSearchHandler) SaveSearch(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+	user, _ := middleware.UserFromContext(r.Context())
+
+	var req models.SavedSearchRequest
+	if err := utils.ParseJSONRequest(r, &req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	req.Name = utils.SanitizeString(req.Name)
+	validationErrors := validateFlightSearchRequest(h.airportService, &req.Query)
+	if len(req.Name) > maxSavedSearchName {
+		validationErrors = append(validationErrors, "name must be at most 100 characters")
+	}
+	if len(validationErrors) > 0 {
+		utils.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Validation failed",
+			Message: "Request validation failed: " + validationErrors[0],
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	search := models.SavedSearch{
+		ID:        utils.NewID(),
+		UserID:    user.ID,
+		Name:      req.Name,
+		Query:     req.Query,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := h.store.SaveSearch(r.Context(), search); err != nil {
+		slog.ErrorContext(r.Context(), "failed to save search", "user_id", user.ID, "error", err)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to save search")
+		return
+	}
+
+	w.Header().Set("Location", "/api/me/searches/"+search.ID)
+	utils.WriteJSONResponse(w, http.StatusCreated, search)
+}
+
+// DeleteSearch removes one of the signed-in user's saved searches
+func (h *SavedSearchHandler) DeleteSearch(w http.ResponseWriter, r *http.Request) {
+	user, _ := middleware.UserFromContext(r.Context())
+
+	if err := h.store.DeleteSavedSearch(r.Context(), user.ID, mux.Vars(r)["id"]); err != nil {
+		writeStoreError(w, r, err, "saved search")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
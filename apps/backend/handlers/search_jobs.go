@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"cheapest-flight-backend/middleware"
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/utils"
+)
+
+type SearchJobHandler struct {
+	jobs           *services.SearchJobManager
+	airportService *services.AirportService
+}
+
+func NewSearchJobHandler(jobs *services.SearchJobManager, airportService *services.AirportService) *SearchJobHandler {
+	return &SearchJobHandler{
+		jobs:           jobs,
+		airportService: airportService,
+	}
+}
+
+// CreateJob queues an asynchronous flight search and returns its ID
+func (h *SearchJobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+
+	var req models.FlightSearchRequest
+	if err := utils.ParseJSONRequest(r, &req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if validationErrors := validateFlightSearchRequest(h.airportService, &req); len(validationErrors) > 0 {
+		utils.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Validation failed",
+			Message: "Request validation failed: " + validationErrors[0],
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	user, _ := middleware.UserFromContext(r.Context())
+	job, err := h.jobs.Submit(r.Context(), user.ID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrJobQueueFull) {
+			w.Header().Set("Retry-After", "30")
+			utils.WriteErrorResponse(w, http.StatusServiceUnavailable, "Too many searches in progress, please try again later")
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to queue search: "+err.Error())
+		return
+	}
+
+	slog.InfoContext(r.Context(), "queued search job",
+		"job_id", job.ID,
+		"origin", req.Origin,
+		"destination", req.Destination,
+		"date", req.Date,
+	)
+	w.Header().Set("Location", "/api/search/jobs/"+job.ID)
+	utils.WriteJSONResponse(w, http.StatusAccepted, job)
+}
+
+// GetJob returns the status, progress and (partial) results of a search job
+func (h *SearchJobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	user, _ := middleware.UserFromContext(r.Context())
+
+	job, exists := h.jobs.Get(r.Context(), id)
+	// Anonymous jobs are readable by anyone with the ID; don't reveal that
+	// other users' jobs exist
+	if !exists || (job.UserID != "" && job.UserID != user.ID) {
+		utils.WriteErrorResponse(w, http.StatusNotFound, "Search job not found or expired")
+		return
+	}
+
+	if job.Status == models.JobCompleted {
+		job.Message = generateResponseMessage(job.Flights)
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, job)
+}
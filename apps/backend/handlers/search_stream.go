@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/utils"
+)
+
+const (
+	streamSearchTimeout = 2 * time.Minute
+	streamHeartbeat     = 15 * time.Second
+)
+
+// sseWriter serializes Server-Sent Events onto a response
+type sseWriter struct {
+	mu sync.Mutex
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (s *sseWriter) send(event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// heartbeat writes a comment line so proxies don't close an idle stream
+func (s *sseWriter) heartbeat() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := fmt.Fprint(s.w, ": keep-alive\n\n"); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// StreamSearch runs a flight search and streams each branch's flights to the
+// client as Server-Sent Events, followed by a final ranked summary
+func (h *FlightSearchHandler) StreamSearch(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+
+	query := r.URL.Query()
+	req := models.FlightSearchRequest{
+		Origin:      query.Get("origin"),
+		Destination: query.Get("destination"),
+		Date:        query.Get("date"),
+		Passengers:  1,
+	}
+	if p := query.Get("passengers"); p != "" {
+		passengers, err := strconv.Atoi(p)
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "passengers must be a number")
+			return
+		}
+		req.Passengers = passengers
+	}
+	if km := query.Get("nearbyRadiusKm"); km != "" {
+		radius, err := strconv.Atoi(km)
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "nearbyRadiusKm must be a number")
+			return
+		}
+		req.NearbyRadiusKm = radius
+	}
+
+	if validationErrors := validateFlightSearchRequest(h.airportService, &req); len(validationErrors) > 0 {
+		utils.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Validation failed",
+			Message: "Request validation failed: " + validationErrors[0],
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if h.amadeusService.Budget().Exhausted() {
+		writeQuotaExhausted(w, h.amadeusService.Budget())
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The server-wide WriteTimeout would otherwise cut the stream off
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.WarnContext(r.Context(), "stream search: could not clear write deadline", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &sseWriter{w: w, rc: rc}
+
+	// The request context is cancelled when the client disconnects
+	ctx, cancel := context.WithTimeout(r.Context(), streamSearchTimeout)
+	ctx, stats := services.WithSearchStats(ctx)
+
+	// Nothing may write to w once the handler returns, so the heartbeat is
+	// stopped and waited for first
+	heartbeatDone := make(chan struct{})
+	defer func() {
+		cancel()
+		<-heartbeatDone
+	}()
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(streamHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if ctx.Err() != nil {
+					return // both were ready and select picked the tick
+				}
+				if err := stream.heartbeat(); err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	// A failed write means the client has gone; stop searching for it
+	var streamErr error
+	send := func(event string, data interface{}) {
+		if streamErr != nil {
+			return
+		}
+		if streamErr = stream.send(event, data); streamErr != nil {
+			slog.WarnContext(r.Context(), "stream search: client disconnected", "event", event, "error", streamErr)
+			cancel()
+		}
+	}
+
+	slog.InfoContext(r.Context(), "streaming cheapest flights",
+		"origin", req.Origin,
+		"destination", req.Destination,
+		"date", req.Date,
+		"passengers", req.Passengers,
+	)
+
+	send("progress", map[string]interface{}{
+		"completed": 0,
+		"total":     3,
+		"status":    "started",
+	})
+
+	flights, err := h.routeOptimizer.OptimizeRoutesWithProgress(ctx, req, func(result services.BranchResult) {
+		if result.Err != nil {
+			send("branch_error", map[string]interface{}{
+				"branch": result.Branch,
+				"error":  result.Err.Error(),
+			})
+		} else {
+			send("flights", map[string]interface{}{
+				"branch":  result.Branch,
+				"flights": result.Flights,
+				"total":   len(result.Flights),
+			})
+		}
+		send("progress", map[string]interface{}{
+			"completed":     result.Completed,
+			"total":         result.Total,
+			"branch":        result.Branch,
+			"upstreamCalls": stats.Calls(),
+		})
+	})
+	if streamErr != nil {
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "stream search failed", "error", err)
+		code, message := http.StatusInternalServerError, "Flight search failed: "+err.Error()
+		if errors.Is(err, services.ErrQuotaExhausted) {
+			code, message = http.StatusServiceUnavailable, "Daily flight search quota exhausted, please try again later"
+		}
+		send("error", models.ErrorResponse{
+			Error:   http.StatusText(code),
+			Message: message,
+			Code:    code,
+		})
+		return
+	}
+
+	response := models.FlightSearchResponse{
+		Flights:       flights,
+		Total:         len(flights),
+		Query:         req,
+		Message:       generateResponseMessage(flights),
+		UpstreamCalls: stats.Calls(),
+		Degraded:      stats.Rejected() > 0,
+	}
+	if response.Degraded {
+		response.Message += " (partial results: daily search quota reached)"
+	}
+	send("summary", response)
+
+	slog.InfoContext(r.Context(), "stream search completed",
+		"origin", req.Origin,
+		"destination", req.Destination,
+		"flights", len(flights),
+		"upstream_calls", stats.Calls(),
+	)
+}
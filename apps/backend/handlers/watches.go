@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"cheapest-flight-backend/middleware"
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/store"
+	"cheapest-flight-backend/utils"
+)
+
+type WatchHandler struct {
+	store          store.WatchStore
+	scheduler      *services.WatchScheduler
+	airportService *services.AirportService
+}
+
+func NewWatchHandler(watchStore store.WatchStore, scheduler *services.WatchScheduler, airportService *services.AirportService) *WatchHandler {
+	return &WatchHandler{
+		store:          watchStore,
+		scheduler:      scheduler,
+		airportService: airportService,
+	}
+}
+
+// CreateWatch subscribes the signed-in user to price changes on a route and
+// date
+func (h *WatchHandler) CreateWatch(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+	user, _ := middleware.UserFromContext(r.Context())
+
+	req, ok := h.parseWatchRequest(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	watch := models.PriceWatch{
+		ID:                utils.NewID(),
+		UserID:            user.ID,
+		PriceWatchRequest: req,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+		Crossings:         []models.PriceWatchCrossing{},
+	}
+
+	if err := h.store.SaveWatch(r.Context(), watch); err != nil {
+		slog.ErrorContext(r.Context(), "failed to save watch", "error", err)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to save watch")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "created watch",
+		"watch_id", watch.ID,
+		"user_id", user.ID,
+		"origin", watch.Origin,
+		"destination", watch.Destination,
+		"date", watch.Date,
+		"max_price", watch.MaxPrice,
+	)
+	w.Header().Set("Location", "/api/watches/"+watch.ID)
+	utils.WriteJSONResponse(w, http.StatusCreated, watch)
+}
+
+// ListWatches returns the signed-in user's price watches
+func (h *WatchHandler) ListWatches(w http.ResponseWriter, r *http.Request) {
+	user, _ := middleware.UserFromContext(r.Context())
+
+	watches, err := h.store.ListUserWatches(r.Context(), user.ID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to list watches", "user_id", user.ID, "error", err)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list watches")
+		return
+	}
+	if watches == nil {
+		watches = []models.PriceWatch{}
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"watches": watches,
+		"total":   len(watches),
+	})
+}
+
+// GetWatch returns a single price watch with its crossing history
+func (h *WatchHandler) GetWatch(w http.ResponseWriter, r *http.Request) {
+	watch, ok := h.ownedWatch(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, watch)
+}
+
+// UpdateWatch replaces the route, date, passengers and threshold of a watch
+func (h *WatchHandler) UpdateWatch(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+
+	watch, ok := h.ownedWatch(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := h.parseWatchRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// A different search invalidates the previous results
+	if req.SearchRequest() != watch.SearchRequest() {
+		watch.LastCheckedAt = nil
+		watch.LastBestPrice = nil
+		watch.LastCurrency = ""
+		watch.LastError = ""
+		watch.BelowThreshold = false
+	}
+	watch.PriceWatchRequest = req
+	watch.UpdatedAt = time.Now().UTC()
+
+	// Moving the threshold itself isn't a price crossing
+	if watch.LastBestPrice != nil {
+		watch.BelowThreshold = *watch.LastBestPrice <= watch.MaxPrice
+	}
+
+	if err := h.store.SaveWatch(r.Context(), watch); err != nil {
+		slog.ErrorContext(r.Context(), "failed to save watch", "watch_id", watch.ID, "error", err)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to save watch")
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, watch)
+}
+
+// CheckWatch re-runs a watch's search immediately instead of waiting for the
+// next scheduled check
+func (h *WatchHandler) CheckWatch(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+
+	if _, ok := h.ownedWatch(w, r); !ok {
+		return
+	}
+	watch, err := h.scheduler.CheckWatch(r.Context(), mux.Vars(r)["id"])
+	if err != nil && watch.ID == "" {
+		writeStoreError(w, r, err, "watch")
+		return
+	}
+
+	// Search failures are recorded on the watch itself
+	utils.WriteJSONResponse(w, http.StatusOK, watch)
+}
+
+// DeleteWatch removes a price watch
+func (h *WatchHandler) DeleteWatch(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+
+	watch, ok := h.ownedWatch(w, r)
+	if !ok {
+		return
+	}
+	if err := h.store.DeleteWatch(r.Context(), watch.ID); err != nil {
+		writeStoreError(w, r, err, "watch")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownedWatch loads the watch named in the URL, writing the error response
+// itself when it doesn't exist or belongs to another user
+func (h *WatchHandler) ownedWatch(w http.ResponseWriter, r *http.Request) (models.PriceWatch, bool) {
+	user, _ := middleware.UserFromContext(r.Context())
+
+	watch, err := h.store.GetWatch(r.Context(), mux.Vars(r)["id"])
+	if err == nil && watch.UserID != user.ID {
+		err = store.ErrNotFound // don't reveal other users' watches
+	}
+	if err != nil {
+		writeStoreError(w, r, err, "watch")
+		return watch, false
+	}
+	return watch, true
+}
+
+// parseWatchRequest decodes and validates a watch body, writing the error
+// response itself when the request is invalid
+func (h *WatchHandler) parseWatchRequest(w http.ResponseWriter, r *http.Request) (models.PriceWatchRequest, bool) {
+	var req models.PriceWatchRequest
+	if err := utils.ParseJSONRequest(r, &req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return req, false
+	}
+
+	search := req.SearchRequest()
+	validationErrors := validateFlightSearchRequest(h.airportService, &search)
+	if req.MaxPrice <= 0 {
+		validationErrors = append(validationErrors, "maxPrice must be greater than 0")
+	}
+	if len(validationErrors) > 0 {
+		utils.WriteJSONResponse(w, http.StatusBadRequest, models.ErrorResponse{
+			Error:   "Validation failed",
+			Message: "Request validation failed: " + validationErrors[0],
+			Code:    http.StatusBadRequest,
+		})
+		return req, false
+	}
+
+	req.Origin = search.Origin
+	req.Destination = search.Destination
+	return req, true
+}
+
+// writeStoreError maps store errors to HTTP responses
+func writeStoreError(w http.ResponseWriter, r *http.Request, err error, resource string) {
+	if errors.Is(err, store.ErrNotFound) {
+		utils.WriteErrorResponse(w, http.StatusNotFound, strings.ToUpper(resource[:1])+resource[1:]+" not found")
+		return
+	}
+	slog.ErrorContext(r.Context(), "failed to load "+resource, "error", err)
+	utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load "+resource)
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"cheapest-flight-backend/middleware"
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/store"
+	"cheapest-flight-backend/utils"
+)
+
+type WebhookHandler struct {
+	store      store.WebhookStore
+	dispatcher *services.WebhookDispatcher
+}
+
+func NewWebhookHandler(webhookStore store.WebhookStore, dispatcher *services.WebhookDispatcher) *WebhookHandler {
+	return &WebhookHandler{
+		store:      webhookStore,
+		dispatcher: dispatcher,
+	}
+}
+
+// CreateWebhook registers a URL for one or more event types on behalf of the
+// signed-in user. The signing secret is only returned in this response.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+	user, _ := middleware.UserFromContext(r.Context())
+
+	var req models.WebhookRequest
+	if err := utils.ParseJSONRequest(r, &req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	if err := validateWebhookRequest(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.dispatcher.CheckURL(r.Context(), req.URL); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Secret == "" {
+		req.Secret = utils.NewID() + utils.NewID()
+	}
+
+	webhook := models.Webhook{
+		ID:        utils.NewID(),
+		UserID:    user.ID,
+		URL:       req.URL,
+		Events:    req.Events,
+		Secret:    req.Secret,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := h.store.SaveWebhook(r.Context(), webhook); err != nil {
+		slog.ErrorContext(r.Context(), "failed to save webhook", "error", err)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to save webhook")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "registered webhook", "webhook_id", webhook.ID, "user_id", user.ID, "events", webhook.Events)
+	w.Header().Set("Location", "/api/webhooks/"+webhook.ID)
+	utils.WriteJSONResponse(w, http.StatusCreated, webhook)
+}
+
+// ListWebhooks returns the signed-in user's webhooks without their secrets
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	user, _ := middleware.UserFromContext(r.Context())
+
+	webhooks, err := h.store.ListUserWebhooks(r.Context(), user.ID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to list webhooks", "user_id", user.ID, "error", err)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list webhooks")
+		return
+	}
+
+	if webhooks == nil {
+		webhooks = []models.Webhook{}
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"webhooks": webhooks,
+		"total":    len(webhooks),
+		"events":   models.WebhookEventTypes,
+	})
+}
+
+// GetWebhook returns a single webhook without its secret
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := h.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	webhook.Secret = ""
+	utils.WriteJSONResponse(w, http.StatusOK, webhook)
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+
+	webhook, ok := h.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+	if err := h.store.DeleteWebhook(r.Context(), webhook.ID); err != nil {
+		writeStoreError(w, r, err, "webhook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries returns the most recent delivery attempts for a webhook
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := h.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+	id := webhook.ID
+
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.store.ListDeliveries(r.Context(), id, limit)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to list deliveries", "webhook_id", id, "error", err)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list deliveries")
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"deliveries": deliveries,
+		"total":      len(deliveries),
+	})
+}
+
+// PingWebhook delivers a test event right away and returns the outcome, so
+// clients can check their receiver and signature verification
+func (h *WebhookHandler) PingWebhook(w http.ResponseWriter, r *http.Request) {
+	utils.LogRequest(r)
+
+	webhook, ok := h.ownedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	delivery := h.dispatcher.Ping(r.Context(), webhook)
+
+	utils.WriteJSONResponse(w, http.StatusOK, delivery)
+}
+
+// ownedWebhook loads the webhook named in the URL, writing the error response
+// itself when it doesn't exist or belongs to another user
+func (h *WebhookHandler) ownedWebhook(w http.ResponseWriter, r *http.Request) (models.Webhook, bool) {
+	user, _ := middleware.UserFromContext(r.Context())
+
+	webhook, err := h.store.GetWebhook(r.Context(), mux.Vars(r)["id"])
+	if err == nil && webhook.UserID != user.ID {
+		err = store.ErrNotFound // don't reveal other users' webhooks
+	}
+	if err != nil {
+		writeStoreError(w, r, err, "webhook")
+		return webhook, false
+	}
+	return webhook, true
+}
+
+// validateWebhookRequest checks the URL and event types
+func validateWebhookRequest(req *models.WebhookRequest) error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+
+	if len(req.Events) == 0 {
+		return fmt.Errorf("events must list at least one of %v", models.WebhookEventTypes)
+	}
+	for _, event := range req.Events {
+		known := false
+		for _, eventType := range models.WebhookEventTypes {
+			if event == eventType {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown event type %q, expected one of %v", event, models.WebhookEventTypes)
+		}
+	}
+
+	return nil
+}
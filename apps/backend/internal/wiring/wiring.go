@@ -0,0 +1,54 @@
+// Package wiring maps configuration onto service settings, so the server and
+// the CLI build their services the same way
+package wiring
+
+import (
+	"cheapest-flight-backend/config"
+	"cheapest-flight-backend/services"
+)
+
+// AmadeusCredentials returns every configured Amadeus credential set, the
+// AMADEUS_API_KEY pair first
+func AmadeusCredentials(cfg *config.Config) []services.AmadeusCredentials {
+	var sets []services.AmadeusCredentials
+	if cfg.AmadeusAPIKey != "" {
+		sets = append(sets, services.AmadeusCredentials{ClientID: cfg.AmadeusAPIKey, ClientSecret: cfg.AmadeusAPISecret})
+	}
+	for _, creds := range cfg.AmadeusCredentials {
+		sets = append(sets, services.AmadeusCredentials{ClientID: creds.APIKey, ClientSecret: creds.APISecret})
+	}
+	return sets
+}
+
+// SearchSettings returns the route optimizer settings
+func SearchSettings(cfg *config.Config) services.SearchSettings {
+	return services.SearchSettings{
+		HubAirports:        cfg.HubAirports,
+		MajorHubs:          cfg.MajorHubs,
+		MaxRelevantHubs:    cfg.MaxRelevantHubs,
+		MaxTwoStopHubs:     cfg.MaxTwoStopHubs,
+		OneStopConcurrency: cfg.OneStopConcurrency,
+		TwoStopConcurrency: cfg.TwoStopConcurrency,
+		TopResults:         cfg.TopResults,
+		MaxNearbyAirports:  cfg.MaxNearbyAirports,
+	}
+}
+
+// ExploreSettings returns the explore candidate search settings
+func ExploreSettings(cfg *config.Config) services.ExploreSettings {
+	return services.ExploreSettings{
+		Destinations: cfg.ExploreDestinations,
+		SampleDates:  cfg.ExploreSampleDates,
+		Concurrency:  cfg.ExploreConcurrency,
+		MaxCalls:     cfg.ExploreMaxCalls,
+	}
+}
+
+// RateLimits returns the per-IP limits for anonymous clients
+func RateLimits(cfg *config.Config) services.RateLimits {
+	return services.RateLimits{
+		PerMinute:  cfg.RateLimitPerMinute,
+		Burst:      cfg.RateLimitBurst,
+		SearchCost: cfg.RateLimitSearchCost,
+	}
+}
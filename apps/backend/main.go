@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"flag"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,11 +13,18 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/rs/cors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"cheapest-flight-backend/config"
 	"cheapest-flight-backend/handlers"
+	"cheapest-flight-backend/internal/wiring"
+	"cheapest-flight-backend/logging"
+	"cheapest-flight-backend/middleware"
+	"cheapest-flight-backend/models"
 	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/store"
+	"cheapest-flight-backend/tracing"
+	"cheapest-flight-backend/utils"
 )
 
 const (
@@ -23,37 +32,180 @@ const (
 )
 
 func main() {
+	// Structured JSON logs; the level is raised or lowered once config is loaded
+	slog.SetDefault(logging.New(os.Stdout, slog.LevelInfo))
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		fatal("failed to load configuration", err)
 	}
+	var logLevel slog.LevelVar
+	logLevel.Set(cfg.LogLevel)
+	slog.SetDefault(logging.New(os.Stdout, &logLevel))
+
+	slog.Info("starting cheapest flight backend",
+		"version", Version,
+		"environment", cfg.Environment,
+		"port", cfg.Port,
+	)
 
-	log.Printf("Starting Cheapest Flight Backend v%s", Version)
-	log.Printf("Environment: %s", cfg.Environment)
-	log.Printf("Port: %s", cfg.Port)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingExporter, Version, cfg.Environment, cfg.TracingSampleRatio)
+	if err != nil {
+		fatal("failed to set up tracing", err)
+	}
 
 	// Initialize services
-	amadeusService := services.NewAmadeusService(cfg.AmadeusBaseURL, cfg.AmadeusAPIKey, cfg.AmadeusAPISecret)
-	airportService := services.NewAirportService()
-	routeOptimizer := services.NewRouteOptimizer(amadeusService)
+	callBudget := services.NewCallBudget(cfg.AmadeusMaxConcurrency, cfg.AmadeusDailyQuota)
+	amadeusService := services.NewAmadeusService(cfg.AmadeusBaseURL, wiring.AmadeusCredentials(cfg), cfg.AmadeusCredentialStrategy, callBudget)
+	amadeusService.Currency = cfg.Currency
+	amadeusService.HTTPClient.Timeout = cfg.AmadeusTimeout
+	airportService, err := services.NewAirportService(cfg.AirportsFile)
+	if err != nil {
+		fatal("failed to load airport data", err)
+	}
+	handlers.LogAirportLoad(context.Background(), airportService.LoadReport())
+
+	// Open the database and bring its schema up to date
+	db, err := store.Open(context.Background(), cfg.DatabaseDriver, cfg.DatabasePath)
+	if err != nil {
+		fatal("failed to open database", err)
+	}
+	defer db.Close()
+	slog.Info("database opened", "driver", cfg.DatabaseDriver, "path", cfg.DatabasePath)
+
+	priceHistory := services.NewPriceHistoryService(db)
+	routeOptimizer := services.NewRouteOptimizer(amadeusService, airportService, priceHistory, wiring.SearchSettings(cfg))
+	exploreService := services.NewExploreService(amadeusService, airportService, priceHistory, wiring.ExploreSettings(cfg))
+	searchJobs := services.NewSearchJobManager(routeOptimizer, db, cfg.SearchJobWorkers, cfg.SearchJobQueue, cfg.SearchJobTimeout, cfg.SearchJobTTL)
+
+	// Background workers stop when the server shuts down
+	appCtx, stopApp := context.WithCancel(context.Background())
+	defer stopApp()
+	searchJobs.Start(appCtx)
+
+	watchScheduler := services.NewWatchScheduler(db, routeOptimizer, cfg.WatchInterval, cfg.WatchBudgetReserve)
+	watchScheduler.Start(appCtx)
+
+	webhookDispatcher := services.NewWebhookDispatcher(db, cfg.WebhookMaxAttempts, cfg.WebhookInitialBackoff, cfg.WebhookTimeout, cfg.WebhookAllowPrivateDestinations)
+	webhookDispatcher.Start(appCtx)
+	if cfg.WebhookAllowPrivateDestinations {
+		slog.Warn("webhooks may reach private, loopback and link-local addresses; only use this for testing")
+	}
+
+	// Fan events out to webhook subscribers
+	watchScheduler.OnCrossing(func(watch models.PriceWatch, crossing models.PriceWatchCrossing) {
+		if crossing.Direction != models.CrossedBelow {
+			return
+		}
+		webhookDispatcher.Publish(appCtx, watch.UserID, models.EventPriceDrop, map[string]interface{}{
+			"watch":    watch,
+			"crossing": crossing,
+		})
+	})
+	searchJobs.OnComplete(func(job models.SearchJob) {
+		webhookDispatcher.Publish(logging.WithRequestID(appCtx, job.RequestID), job.UserID, models.EventSearchCompleted, job)
+	})
+
+	jwtSecret := cfg.JWTSecret
+	if jwtSecret == "" {
+		slog.Warn("JWT_SECRET is not set; using a random key, so sign-ins won't survive a restart")
+		jwtSecret = utils.NewID() + utils.NewID()
+	}
+	authService := services.NewAuthService(db, jwtSecret, cfg.JWTTTL)
+	apiKeyService := services.NewAPIKeyService(db, cfg.APIKeyRatePerMinute, cfg.APIKeyDailySearches)
 
 	// Initialize handlers
-	healthHandler := handlers.NewHealthHandler(Version)
-	flightHandler := handlers.NewFlightSearchHandler(routeOptimizer, amadeusService, airportService)
+	healthHandler := handlers.NewHealthHandler(Version, callBudget)
+	flightHandler := handlers.NewFlightSearchHandler(routeOptimizer, amadeusService, airportService, cfg.SearchTimeout)
+	airportHandler := handlers.NewAirportHandler(airportService)
+	exploreHandler := handlers.NewExploreHandler(exploreService, amadeusService, airportService, cfg.SearchTimeout)
+	searchJobHandler := handlers.NewSearchJobHandler(searchJobs, airportService)
+	watchHandler := handlers.NewWatchHandler(db, watchScheduler, airportService)
+	webhookHandler := handlers.NewWebhookHandler(db, webhookDispatcher)
+	priceHandler := handlers.NewPriceHandler(priceHistory, airportService)
+	authHandler := handlers.NewAuthHandler(authService)
+	savedSearchHandler := handlers.NewSavedSearchHandler(db, airportService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
 
 	// Create router
 	r := mux.NewRouter()
+	r.Use(middleware.Tracing)
+	r.Use(middleware.Metrics)
+	r.Use(middleware.Authenticate(authService))
+	r.Use(middleware.APIKeys(apiKeyService))
+	// Always installed so a reload can switch per-IP limiting on or off
+	rateLimiter := services.NewRateLimiter(wiring.RateLimits(cfg))
+	rateLimiter.Start(appCtx)
+	r.Use(middleware.RateLimit(rateLimiter, cfg.TrustedProxies))
+	if cfg.RateLimitPerMinute > 0 {
+		slog.Info("per-IP rate limit enabled",
+			"per_minute", cfg.RateLimitPerMinute,
+			"burst", cfg.RateLimitBurst,
+			"search_cost", cfg.RateLimitSearchCost,
+			"trusted_proxies", len(cfg.TrustedProxies),
+		)
+	}
 
 	// Health check routes
 	r.HandleFunc("/health", healthHandler.HealthCheck).Methods("GET")
 	r.HandleFunc("/health/ready", healthHandler.ReadinessCheck).Methods("GET")
 	r.HandleFunc("/health/live", healthHandler.LivenessCheck).Methods("GET")
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
 
 	// Flight search routes
-	r.HandleFunc("/api/search", flightHandler.SearchFlights).Methods("POST")
+	r.HandleFunc("/api/search", flightHandler.SearchFlights).Methods("POST").Name(middleware.RouteSearch)
 	r.HandleFunc("/api/search/health", flightHandler.HealthCheck).Methods("GET")
-	r.HandleFunc("/api/airports", flightHandler.GetSupportedAirports).Methods("GET")
+	r.HandleFunc("/api/search/stream", flightHandler.StreamSearch).Methods("GET").Name(middleware.RouteSearchStream)
+	r.HandleFunc("/api/search/jobs", searchJobHandler.CreateJob).Methods("POST").Name(middleware.RouteSearchJobs)
+	r.HandleFunc("/api/search/jobs/{id}", searchJobHandler.GetJob).Methods("GET")
+	r.HandleFunc("/api/explore", exploreHandler.Explore).Methods("POST").Name(middleware.RouteExplore)
+	r.HandleFunc("/api/airports", airportHandler.ListAirports).Methods("GET")
+	r.HandleFunc("/api/airports/nearest", airportHandler.NearestAirports).Methods("GET")
+	r.HandleFunc("/api/airports/{code}", airportHandler.GetAirport).Methods("GET")
+	r.HandleFunc("/api/countries", airportHandler.ListCountries).Methods("GET")
+	r.HandleFunc("/api/prices/history", priceHandler.GetPriceHistory).Methods("GET")
+
+	// Price watch routes, scoped to the signed-in user
+	watches := r.PathPrefix("/api/watches").Subrouter()
+	watches.Use(middleware.RequireUser)
+	watches.HandleFunc("", watchHandler.CreateWatch).Methods("POST")
+	watches.HandleFunc("", watchHandler.ListWatches).Methods("GET")
+	watches.HandleFunc("/{id}", watchHandler.GetWatch).Methods("GET")
+	watches.HandleFunc("/{id}", watchHandler.UpdateWatch).Methods("PUT")
+	watches.HandleFunc("/{id}", watchHandler.DeleteWatch).Methods("DELETE")
+	watches.HandleFunc("/{id}/check", watchHandler.CheckWatch).Methods("POST")
+
+	// Webhook routes, scoped to the signed-in user
+	webhooks := r.PathPrefix("/api/webhooks").Subrouter()
+	webhooks.Use(middleware.RequireUser)
+	webhooks.HandleFunc("", webhookHandler.CreateWebhook).Methods("POST")
+	webhooks.HandleFunc("", webhookHandler.ListWebhooks).Methods("GET")
+	webhooks.HandleFunc("/{id}", webhookHandler.GetWebhook).Methods("GET")
+	webhooks.HandleFunc("/{id}", webhookHandler.DeleteWebhook).Methods("DELETE")
+	webhooks.HandleFunc("/{id}/deliveries", webhookHandler.ListDeliveries).Methods("GET")
+	webhooks.HandleFunc("/{id}/ping", webhookHandler.PingWebhook).Methods("POST")
+
+	// Account routes
+	r.HandleFunc("/api/auth/register", authHandler.Register).Methods("POST")
+	r.HandleFunc("/api/auth/login", authHandler.Login).Methods("POST")
+
+	me := r.PathPrefix("/api/me").Subrouter()
+	me.Use(middleware.RequireUser)
+	me.HandleFunc("/searches", savedSearchHandler.ListSearches).Methods("GET")
+	me.HandleFunc("/searches", savedSearchHandler.SaveSearch).Methods("POST")
+	me.HandleFunc("/searches/{id}", savedSearchHandler.DeleteSearch).Methods("DELETE")
+
+	// Admin routes
+	admin := r.PathPrefix("/api/admin").Subrouter()
+	admin.Use(middleware.RequireAdmin(cfg.AdminToken))
+	admin.HandleFunc("/keys", apiKeyHandler.CreateKey).Methods("POST")
+	admin.HandleFunc("/keys", apiKeyHandler.ListKeys).Methods("GET")
+	admin.HandleFunc("/keys/{id}", apiKeyHandler.RevokeKey).Methods("DELETE")
+	admin.HandleFunc("/airports/reload", airportHandler.ReloadAirports).Methods("POST")
 
 	// API info route
 	r.HandleFunc("/api/info", func(w http.ResponseWriter, r *http.Request) {
@@ -62,10 +214,30 @@ func main() {
 			"version":     Version,
 			"environment": cfg.Environment,
 			"endpoints": map[string]string{
-				"health":        "GET /health",
-				"search":        "POST /api/search",
-				"airports":      "GET /api/airports",
-				"search_health": "GET /api/search/health",
+				"health":         "GET /health",
+				"metrics":        "GET /metrics",
+				"search":         "POST /api/search",
+				"explore":        "POST /api/explore",
+				"airports":       "GET /api/airports",
+				"airport":        "GET /api/airports/{code}",
+				"airports_near":  "GET /api/airports/nearest",
+				"countries":      "GET /api/countries",
+				"price_history":  "GET /api/prices/history",
+				"search_health":  "GET /api/search/health",
+				"search_stream":  "GET /api/search/stream",
+				"search_jobs":    "POST /api/search/jobs",
+				"search_job":     "GET /api/search/jobs/{id}",
+				"watches":        "GET|POST /api/watches",
+				"watch":          "GET|PUT|DELETE /api/watches/{id}",
+				"webhooks":       "GET|POST /api/webhooks",
+				"webhook":        "GET|DELETE /api/webhooks/{id}",
+				"register":       "POST /api/auth/register",
+				"login":          "POST /api/auth/login",
+				"saved_searches": "GET|POST /api/me/searches",
+				"saved_search":   "DELETE /api/me/searches/{id}",
+				"admin_keys":     "GET|POST /api/admin/keys",
+				"admin_key":      "DELETE /api/admin/keys/{id}",
+				"admin_airports": "POST /api/admin/airports/reload",
 			},
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -74,64 +246,95 @@ func main() {
 	}).Methods("GET")
 
 	// Setup CORS
-	c := cors.New(cors.Options{
-		AllowedOrigins:   cfg.AllowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"*"},
-		AllowCredentials: false,
-		MaxAge:           300, // 5 minutes
-	})
+	corsPolicy := middleware.NewCORS(cfg.AllowedOrigins)
 
-	handler := c.Handler(r)
+	// Request IDs and access logs wrap everything, including CORS preflights
+	// and unmatched routes
+	handler := middleware.RequestID(middleware.AccessLog(cfg.TrustedProxies)(corsPolicy.Handler(r)))
 
 	// Setup server
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
 		Handler:      handler,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 60 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		ReadTimeout:  cfg.ServerReadTimeout,
+		WriteTimeout: cfg.ServerWriteTimeout,
+		IdleTimeout:  cfg.ServerIdleTimeout,
 	}
 
 	// Setup graceful shutdown
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
+	// Reload the settings that are safe to change while running on SIGHUP
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		current := cfg
+		for range reload {
+			next, err := config.Load(os.Args[1:])
+			if err != nil {
+				slog.Error("configuration reload failed; keeping the current settings", "error", err)
+				continue
+			}
+			if changed := current.RestartRequired(next); len(changed) > 0 {
+				slog.Warn("some changed settings only take effect after a restart", "settings", changed)
+			}
+
+			logLevel.Set(next.LogLevel)
+			routeOptimizer.SetSettings(wiring.SearchSettings(next))
+			exploreService.SetSettings(wiring.ExploreSettings(next))
+			rateLimiter.SetLimits(wiring.RateLimits(next))
+			apiKeyService.SetDefaults(next.APIKeyRatePerMinute, next.APIKeyDailySearches)
+			corsPolicy.SetAllowedOrigins(next.AllowedOrigins)
+			current = next
+			slog.Info("configuration reloaded",
+				"allowed_origins", next.AllowedOrigins,
+				"rate_limit_per_minute", next.RateLimitPerMinute,
+				"top_results", next.TopResults,
+			)
+		}
+	}()
+
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Server starting on port %s", cfg.Port)
+		slog.Info("server starting", "port", cfg.Port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
+			fatal("server failed to start", err)
 		}
 	}()
 
 	// Test Amadeus connection on startup
 	go func() {
 		time.Sleep(2 * time.Second) // Give server time to start
-		log.Printf("Testing Amadeus API connection...")
-		if err := amadeusService.HealthCheck(); err != nil {
-			log.Printf("Warning: Amadeus API connection failed: %v", err)
-			log.Printf("The service will continue but flight searches may not work properly")
+		slog.Info("testing Amadeus API connection")
+		if err := amadeusService.HealthCheck(appCtx); err != nil {
+			slog.Warn("Amadeus API connection failed; flight searches may not work properly", "error", err)
 		} else {
-			log.Printf("Amadeus API connection successful")
+			slog.Info("Amadeus API connection successful")
 		}
-
-		// Log airport service status
-		airports := airportService.GetAllAirports()
-		log.Printf("Airport service loaded with %d airports", len(airports))
 	}()
 
 	// Wait for interrupt signal
 	<-stop
-	log.Printf("Shutting down server...")
+	slog.Info("shutting down server")
+	stopApp()
 
 	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Printf("Server forced to shutdown: %v", err)
+		slog.Error("server forced to shutdown", "error", err)
 	} else {
-		log.Printf("Server exited gracefully")
+		slog.Info("server exited gracefully")
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("failed to flush traces", "error", err)
 	}
 }
+
+// fatal logs an unrecoverable startup error and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "cheapest_flight"
+
+// Amadeus endpoints used as the "endpoint" label
+const (
+	EndpointToken              = "token"
+	EndpointFlightOffers       = "flight_offers"
+	EndpointFlightDestinations = "flight_destinations"
+)
+
+var (
+	// HTTPRequests counts served requests by route template, method and status
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by route, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPRequestDuration measures request latency by route template and method
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by route and method.",
+		Buckets:   []float64{0.005, 0.025, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"route", "method"})
+
+	// SearchFanout is the number of Amadeus calls a single search made
+	SearchFanout = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "search_fanout_calls",
+		Help:      "Outbound Amadeus calls made by one flight search.",
+		Buckets:   []float64{1, 5, 10, 25, 50, 100, 150, 200, 300},
+	})
+
+	// SearchResults is the number of flights a single search returned
+	SearchResults = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "search_results",
+		Help:      "Flights returned by one flight search.",
+		Buckets:   []float64{0, 1, 2, 3, 5, 7, 10},
+	})
+
+	// AmadeusRequests counts outbound Amadeus calls by endpoint and status
+	AmadeusRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "amadeus_requests_total",
+		Help:      "Outbound Amadeus API calls, by endpoint and HTTP status (\"error\" when no response was received).",
+	}, []string{"endpoint", "status"})
+
+	// AmadeusRequestDuration measures outbound Amadeus latency by endpoint
+	AmadeusRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "amadeus_request_duration_seconds",
+		Help:      "Outbound Amadeus API call latency, by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	// TokenRefreshes counts OAuth token fetches by outcome
+	TokenRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "amadeus_token_refreshes_total",
+		Help:      "Amadeus OAuth token refreshes, by result.",
+	}, []string{"result"})
+
+	// TokenCache counts access token lookups served from cache (hit) or
+	// requiring a refresh (miss); the hit ratio is hits over all lookups
+	TokenCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "amadeus_token_cache_total",
+		Help:      "Amadeus access token cache lookups, by result (hit or miss).",
+	}, []string{"result"})
+
+	// CredentialFailovers counts flight searches retried with another
+	// credential set after Amadeus refused the previous one
+	CredentialFailovers = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "amadeus_credential_failovers_total",
+		Help:      "Amadeus calls retried with the next credential set after a rejection.",
+	})
+)
+
+// ObserveAmadeusCall records one outbound call. A status of 0 means the call
+// failed before a response arrived.
+func ObserveAmadeusCall(endpoint string, status int, seconds float64) {
+	label := "error"
+	if status > 0 {
+		label = strconv.Itoa(status)
+	}
+	AmadeusRequests.WithLabelValues(endpoint, label).Inc()
+	AmadeusRequestDuration.WithLabelValues(endpoint).Observe(seconds)
+}
+
+// ObserveSearch records the fan-out and result count of a finished search
+func ObserveSearch(upstreamCalls, results int) {
+	SearchFanout.Observe(float64(upstreamCalls))
+	SearchResults.Observe(float64(results))
+}
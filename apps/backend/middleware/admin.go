@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"cheapest-flight-backend/utils"
+)
+
+// AdminTokenHeader carries the operator token for admin endpoints
+const AdminTokenHeader = "X-Admin-Token"
+
+// RequireAdmin guards operator-only endpoints with a shared token. With no
+// token configured the admin endpoints are disabled.
+func RequireAdmin(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				utils.WriteErrorResponse(w, http.StatusForbidden, "Admin API is disabled: ADMIN_TOKEN is not set")
+				return
+			}
+			presented := r.Header.Get(AdminTokenHeader)
+			if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				utils.WriteErrorResponse(w, http.StatusUnauthorized, "Missing or invalid admin token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
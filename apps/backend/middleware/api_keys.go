@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/utils"
+)
+
+// APIKeyHeader carries the key third-party consumers authenticate with
+const APIKeyHeader = "X-API-Key"
+
+// Names of the routes that start a flight search. main.go names these routes
+// so quota middleware can tell them apart from cheap lookups.
+const (
+	RouteSearch       = "search"
+	RouteSearchStream = "search_stream"
+	RouteSearchJobs   = "search_jobs"
+	RouteExplore      = "explore"
+)
+
+const apiKeyContextKey contextKey = iota + 1
+
+// APIKeyFromContext returns the API key attached by APIKeys
+func APIKeyFromContext(ctx context.Context) (models.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey).(models.APIKey)
+	return key, ok
+}
+
+// IsSearchRequest reports whether the request matched a route that starts a
+// flight search
+func IsSearchRequest(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	switch route.GetName() {
+	case RouteSearch, RouteSearchStream, RouteSearchJobs, RouteExplore:
+		return true
+	}
+	return false
+}
+
+// APIKeys authenticates requests that present an API key and meters them
+// against the key's quotas, reporting what is left in rate-limit headers.
+// Requests without a key pass through untouched.
+func APIKeys(keys *services.APIKeyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			plaintext := r.Header.Get(APIKeyHeader)
+			if plaintext == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := keys.Authenticate(r.Context(), plaintext)
+			if err != nil {
+				if errors.Is(err, services.ErrInvalidAPIKey) {
+					utils.WriteErrorResponse(w, http.StatusUnauthorized, "Invalid or revoked API key")
+					return
+				}
+				slog.ErrorContext(r.Context(), "failed to authenticate API key", "error", err)
+				utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to authenticate API key")
+				return
+			}
+
+			search := IsSearchRequest(r)
+			usage, err := keys.Consume(key, search)
+			writeQuotaHeaders(w, usage)
+
+			switch {
+			case errors.Is(err, services.ErrRateLimited):
+				w.Header().Set("Retry-After", retryAfter(usage.RequestsReset))
+				utils.WriteErrorResponse(w, http.StatusTooManyRequests, "Request rate limit exceeded for this API key")
+				return
+			case errors.Is(err, services.ErrSearchQuotaReached):
+				w.Header().Set("Retry-After", retryAfter(usage.SearchesReset))
+				utils.WriteErrorResponse(w, http.StatusTooManyRequests, "Daily search quota reached for this API key")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey, key)))
+		})
+	}
+}
+
+// writeQuotaHeaders reports the key's per-minute request window in the
+// conventional X-RateLimit-* headers and its daily searches in X-Search-Quota-*
+func writeQuotaHeaders(w http.ResponseWriter, usage models.APIKeyUsage) {
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(usage.RequestLimit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(max(usage.RequestLimit-usage.RequestsUsed, 0)))
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(usage.RequestsReset.Unix(), 10))
+	h.Set("X-Search-Quota-Limit", strconv.Itoa(usage.SearchLimit))
+	h.Set("X-Search-Quota-Remaining", strconv.Itoa(max(usage.SearchLimit-usage.SearchesUsed, 0)))
+	h.Set("X-Search-Quota-Reset", strconv.FormatInt(usage.SearchesReset.Unix(), 10))
+}
+
+// retryAfter formats the whole seconds until t for a Retry-After header
+func retryAfter(t time.Time) string {
+	return strconv.Itoa(max(int(math.Ceil(time.Until(t).Seconds())), 1))
+}
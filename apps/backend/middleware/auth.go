@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/utils"
+)
+
+type contextKey int
+
+const userContextKey contextKey = iota
+
+// UserFromContext returns the signed-in user attached by Authenticate
+func UserFromContext(ctx context.Context) (models.User, bool) {
+	user, ok := ctx.Value(userContextKey).(models.User)
+	return user, ok
+}
+
+// Authenticate resolves a "Bearer" token to its user and stores the user in
+// the request context. Requests without a token pass through anonymously;
+// requests with a bad token are rejected.
+func Authenticate(auth *services.AuthService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, found := strings.CutPrefix(header, "Bearer ")
+			if !found || strings.TrimSpace(token) == "" {
+				writeUnauthorized(w, "Authorization header must use the Bearer scheme")
+				return
+			}
+
+			user, err := auth.Authenticate(r.Context(), strings.TrimSpace(token))
+			if err != nil {
+				if !errors.Is(err, services.ErrInvalidToken) {
+					slog.ErrorContext(r.Context(), "failed to authenticate request", "error", err)
+					utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to authenticate request")
+					return
+				}
+				writeUnauthorized(w, "Invalid or expired token")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, user)))
+		})
+	}
+}
+
+// RequireUser rejects requests that Authenticate didn't attach a user to
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := UserFromContext(r.Context()); !ok {
+			writeUnauthorized(w, "Sign in to access this resource")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="cheapest-flight"`)
+	utils.WriteErrorResponse(w, http.StatusUnauthorized, message)
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/rs/cors"
+)
+
+// CORS applies the cross-origin policy. The allowed origins can be replaced
+// while the server is running.
+type CORS struct {
+	policy atomic.Pointer[cors.Cors]
+}
+
+func NewCORS(allowedOrigins []string) *CORS {
+	c := &CORS{}
+	c.SetAllowedOrigins(allowedOrigins)
+	return c
+}
+
+// SetAllowedOrigins swaps in a policy for the given origins
+func (c *CORS) SetAllowedOrigins(allowedOrigins []string) {
+	c.policy.Store(cors.New(cors.Options{
+		AllowedOrigins: allowedOrigins,
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"*"},
+		ExposedHeaders: []string{
+			"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset",
+			"X-Search-Quota-Limit", "X-Search-Quota-Remaining", "X-Search-Quota-Reset",
+			"Retry-After", RequestIDHeader,
+		},
+		AllowCredentials: false,
+		MaxAge:           300, // 5 minutes
+	}))
+}
+
+// Handler wraps next with the current policy
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.policy.Load().ServeHTTP(w, r, next.ServeHTTP)
+	})
+}
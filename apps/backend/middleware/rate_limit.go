@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cheapest-flight-backend/services"
+	"cheapest-flight-backend/utils"
+)
+
+// RateLimit throttles anonymous clients by IP address. Searches cost the
+// limiter's search cost and everything else one token. Requests made with an
+// API key are metered by APIKeys instead, and health probes and metrics
+// scrapes are never limited. Nothing is limited while the limiter is
+// disabled.
+func RateLimit(limiter *services.RateLimiter, trustedProxies []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := APIKeyFromContext(r.Context()); ok || !limiter.Enabled() || strings.HasPrefix(r.URL.Path, "/health") || r.URL.Path == "/metrics" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cost := 1
+			if IsSearchRequest(r) {
+				cost = limiter.SearchCost()
+			}
+
+			clientIP := utils.GetClientIP(r, trustedProxies)
+			decision := limiter.Allow(clientIP, cost)
+
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+			h.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(decision.ResetAfter).Unix(), 10))
+
+			if !decision.Allowed {
+				slog.WarnContext(r.Context(), "rate limited", "method", r.Method, "path", r.URL.Path, "client_ip", clientIP, "cost", cost)
+				h.Set("Retry-After", retryAfter(time.Now().Add(decision.RetryAfter)))
+				utils.WriteErrorResponse(w, http.StatusTooManyRequests, "Too many requests, please slow down")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+
+	"cheapest-flight-backend/logging"
+	"cheapest-flight-backend/utils"
+)
+
+// RequestIDHeader carries the ID that ties together every log line, upstream
+// call and response for one request
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied IDs so they can't bloat the logs
+const maxRequestIDLength = 128
+
+// RequestID accepts the caller's X-Request-ID, or generates one, stores it in
+// the request context and echoes it in the response
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = utils.NewID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID allows printable ASCII without spaces, up to a length limit
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// APIKeyRequest is the body for issuing an API key. Zero limits fall back to
+// the server defaults.
+type APIKeyRequest struct {
+	Name              string `json:"name"`
+	RequestsPerMinute int    `json:"requestsPerMinute,omitempty"`
+	DailySearches     int    `json:"dailySearches,omitempty"`
+}
+
+// APIKey identifies a third-party consumer. Only a hash of the key is
+// stored; the key itself is shown once, when it is issued.
+type APIKey struct {
+	ID                string     `json:"id"`
+	Name              string     `json:"name"`
+	Prefix            string     `json:"prefix"`
+	KeyHash           string     `json:"-"`
+	RequestsPerMinute int        `json:"requestsPerMinute"`
+	DailySearches     int        `json:"dailySearches"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	RevokedAt         *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Revoked reports whether the key has been revoked
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// IssuedAPIKey is returned when a key is created and is the only response
+// that carries the plaintext key
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyUsage is a key's consumption of its quotas in the current windows
+type APIKeyUsage struct {
+	RequestLimit  int       `json:"requestLimit"`
+	RequestsUsed  int       `json:"requestsUsed"`
+	RequestsReset time.Time `json:"requestsReset"`
+	SearchLimit   int       `json:"searchLimit"`
+	SearchesUsed  int       `json:"searchesUsed"`
+	SearchesReset time.Time `json:"searchesReset"`
+}
+
+// APIKeyStatus is an API key together with its current usage
+type APIKeyStatus struct {
+	APIKey
+	Usage APIKeyUsage `json:"usage"`
+}
@@ -0,0 +1,47 @@
+package models
+
+// Hub combination outcomes reported by explain mode
+const (
+	CombinationRan       = "ran"
+	CombinationCancelled = "cancelled" // the search context ended before it started
+	CombinationExcluded  = "excluded"  // a hub is the search's own origin or destination
+)
+
+// SearchDiagnostics explains how a search produced its results
+type SearchDiagnostics struct {
+	AirportPairs     []string            `json:"airportPairs"` // origin-destination pairs searched, the requested one first
+	RelevantHubs     []string            `json:"relevantHubs"`
+	MajorHubs        []string            `json:"majorHubs"`
+	Branches         []BranchDiagnostics `json:"branches"`
+	Combinations     []HubCombination    `json:"combinations"`
+	UpstreamCalls    int                 `json:"upstreamCalls"`
+	TokenCacheHits   int                 `json:"tokenCacheHits"`
+	TokenCacheMisses int                 `json:"tokenCacheMisses"`
+	Candidates       int                 `json:"candidates"`
+	Dropped          []DroppedFlight     `json:"dropped"`
+}
+
+// BranchDiagnostics describes one search branch
+type BranchDiagnostics struct {
+	Branch          string `json:"branch"`
+	DurationMs      int64  `json:"durationMs"`
+	UpstreamCalls   int    `json:"upstreamCalls"`
+	UpstreamLatency int64  `json:"upstreamLatencyMs"` // total time spent in Amadeus calls
+	Flights         int    `json:"flights"`
+	Error           string `json:"error,omitempty"`
+}
+
+// HubCombination records whether a connection through Hubs was searched
+type HubCombination struct {
+	Branch string   `json:"branch"`
+	Hubs   []string `json:"hubs"`
+	Status string   `json:"status"`
+}
+
+// DroppedFlight is a candidate that did not make the final results
+type DroppedFlight struct {
+	ID     string   `json:"id"`
+	Route  []string `json:"route"`
+	Price  float64  `json:"price"`
+	Reason string   `json:"reason"`
+}
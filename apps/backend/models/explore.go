@@ -0,0 +1,71 @@
+package models
+
+// Where explore results came from
+const (
+	ExploreSourceInspiration = "inspiration" // the Amadeus flight inspiration endpoint
+	ExploreSourceCandidates  = "candidates"  // searches to the configured candidate destinations
+)
+
+// ExploreRequest asks for the cheapest one-way fares for one adult from an
+// origin within a departure window and a budget
+type ExploreRequest struct {
+	Origin        string  `json:"origin"`
+	DepartureFrom string  `json:"departureFrom"`
+	DepartureTo   string  `json:"departureTo,omitempty"` // defaults to DepartureFrom
+	MaxPrice      float64 `json:"maxPrice"`
+	Limit         int     `json:"limit,omitempty"` // defaults to 20
+}
+
+// ExploreDestination is the best fare found to one destination, with what
+// the airport data knows about it
+type ExploreDestination struct {
+	Destination   string  `json:"destination"`
+	Name          string  `json:"name,omitempty"`
+	City          string  `json:"city,omitempty"`
+	CountryCode   string  `json:"countryCode,omitempty"`
+	Latitude      float64 `json:"latitude,omitempty"`
+	Longitude     float64 `json:"longitude,omitempty"`
+	Price         float64 `json:"price"`
+	Currency      string  `json:"currency"`
+	DepartureDate string  `json:"departureDate"`
+	Airline       string  `json:"airline,omitempty"` // only known for candidate searches
+	Stops         *int    `json:"stops,omitempty"`   // only known for candidate searches
+}
+
+// ExploreResponse ranks destinations from cheapest to dearest
+type ExploreResponse struct {
+	Destinations  []ExploreDestination `json:"destinations"`
+	Total         int                  `json:"total"`
+	Query         ExploreRequest       `json:"query"`
+	Source        string               `json:"source"`
+	UpstreamCalls int                  `json:"upstreamCalls"`
+	Degraded      bool                 `json:"degraded,omitempty"`
+	Message       string               `json:"message,omitempty"`
+}
+
+// AmadeusDestinationResponse is the Amadeus flight inspiration response
+type AmadeusDestinationResponse struct {
+	Data []AmadeusFlightDestination `json:"data"`
+	Meta AmadeusDestinationMeta     `json:"meta"`
+}
+
+// AmadeusFlightDestination is the cheapest fare Amadeus knows to one
+// destination
+type AmadeusFlightDestination struct {
+	Type          string                  `json:"type"`
+	Origin        string                  `json:"origin"`
+	Destination   string                  `json:"destination"`
+	DepartureDate string                  `json:"departureDate"`
+	ReturnDate    string                  `json:"returnDate"`
+	Price         AmadeusDestinationPrice `json:"price"`
+}
+
+// AmadeusDestinationPrice is the fare of a flight inspiration result
+type AmadeusDestinationPrice struct {
+	Total string `json:"total"`
+}
+
+// AmadeusDestinationMeta carries the currency inspiration fares are quoted in
+type AmadeusDestinationMeta struct {
+	Currency string `json:"currency"`
+}
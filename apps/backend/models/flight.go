@@ -2,15 +2,23 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"time"
 )
 
+// MaxNearbyRadiusKm is the widest radius searched for alternate airports
+const MaxNearbyRadiusKm = 300
+
 // FlightSearchRequest represents the incoming search request
 type FlightSearchRequest struct {
 	Origin      string `json:"origin" validate:"required,len=3"`
 	Destination string `json:"destination" validate:"required,len=3"`
 	Date        string `json:"date" validate:"required"`
 	Passengers  int    `json:"passengers" validate:"required,min=1,max=9"`
+
+	// Also search every airport within this many km of the origin and
+	// destination; 0 searches only the airports given
+	NearbyRadiusKm int `json:"nearbyRadiusKm,omitempty"`
 }
 
 // Validate validates the flight search request
@@ -40,10 +48,20 @@ func (r *FlightSearchRequest) Validate() error {
 	if r.Passengers < 1 || r.Passengers > 9 {
 		return errors.New("passengers must be between 1 and 9")
 	}
+	if r.NearbyRadiusKm < 0 || r.NearbyRadiusKm > MaxNearbyRadiusKm {
+		return fmt.Errorf("nearby radius must be between 0 and %d km", MaxNearbyRadiusKm)
+	}
 
 	return nil
 }
 
+// Tags of search results that explain the tradeoff between them
+const (
+	FlightTagCheapest  = "cheapest"
+	FlightTagFastest   = "fastest"
+	FlightTagBestValue = "best_value" // the best balance of price and travel time
+)
+
 // Flight represents a flight option
 type Flight struct {
 	ID          string   `json:"id"`
@@ -57,14 +75,57 @@ type Flight struct {
 	Stops       int      `json:"stops"`
 	Route       []string `json:"route"`
 	BookingURL  string   `json:"bookingUrl,omitempty"`
+
+	// Total travel time, or 0 when Amadeus didn't give one
+	DurationMinutes int `json:"durationMinutes,omitempty"`
+
+	// Why the flight is worth a look: FlightTagCheapest, FlightTagFastest
+	// and/or FlightTagBestValue
+	Tags []string `json:"tags,omitempty"`
+
+	// How far the airports actually used are from the ones searched for,
+	// when a nearby alternate was used instead
+	OriginDistanceKm      float64 `json:"originDistanceKm,omitempty"`
+	DestinationDistanceKm float64 `json:"destinationDistanceKm,omitempty"`
 }
 
 // FlightSearchResponse represents the response to a flight search
 type FlightSearchResponse struct {
-	Flights []Flight            `json:"flights"`
-	Message string              `json:"message,omitempty"`
-	Total   int                 `json:"total"`
-	Query   FlightSearchRequest `json:"query"`
+	Flights       []Flight            `json:"flights"`
+	Message       string              `json:"message,omitempty"`
+	Total         int                 `json:"total"`
+	Query         FlightSearchRequest `json:"query"`
+	UpstreamCalls int                 `json:"upstreamCalls"`
+	Degraded      bool                `json:"degraded,omitempty"`
+	Diagnostics   *SearchDiagnostics  `json:"diagnostics,omitempty"` // only with explain=true
+}
+
+// Search job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// SearchJob represents an asynchronous flight search and its partial results
+type SearchJob struct {
+	ID                string              `json:"id"`
+	Status            string              `json:"status"`
+	Progress          float64             `json:"progress"`
+	CompletedBranches []string            `json:"completedBranches"`
+	Query             FlightSearchRequest `json:"query"`
+	UserID            string              `json:"-"`
+	Flights           []Flight            `json:"flights"`
+	Total             int                 `json:"total"`
+	Message           string              `json:"message,omitempty"`
+	Error             string              `json:"error,omitempty"`
+	UpstreamCalls     int                 `json:"upstreamCalls"`
+	RequestID         string              `json:"requestId,omitempty"`
+	CreatedAt         time.Time           `json:"createdAt"`
+	StartedAt         *time.Time          `json:"startedAt,omitempty"`
+	CompletedAt       *time.Time          `json:"completedAt,omitempty"`
+	ExpiresAt         time.Time           `json:"expiresAt"`
 }
 
 // AmadeusTokenRequest represents the token request to Amadeus
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Price verdicts for today's best fare compared with its history
+const (
+	PriceLow     = "low"
+	PriceTypical = "typical"
+	PriceHigh    = "high"
+	PriceUnknown = "unknown"
+)
+
+// PriceObservation is a single fare seen in an Amadeus search result
+type PriceObservation struct {
+	Origin      string    `json:"origin"`
+	Destination string    `json:"destination"`
+	TravelDate  string    `json:"travelDate"`
+	ObservedAt  time.Time `json:"observedAt"`
+	Price       float64   `json:"price"`
+	Currency    string    `json:"currency"`
+	Carrier     string    `json:"carrier"`
+	Stops       int       `json:"stops"`
+}
+
+// PriceTrendPoint aggregates the fares observed on one search day
+type PriceTrendPoint struct {
+	Day          string  `json:"day"`
+	MinPrice     float64 `json:"minPrice"`
+	AvgPrice     float64 `json:"avgPrice"`
+	MaxPrice     float64 `json:"maxPrice"`
+	Observations int     `json:"observations"`
+}
+
+// PriceSummary compares today's best fare with earlier days
+type PriceSummary struct {
+	Verdict          string   `json:"verdict"`
+	TodayBest        *float64 `json:"todayBest,omitempty"`
+	HistoricalLow    *float64 `json:"historicalLow,omitempty"`
+	HistoricalMedian *float64 `json:"historicalMedian,omitempty"`
+	HistoricalHigh   *float64 `json:"historicalHigh,omitempty"`
+	SampleDays       int      `json:"sampleDays"`
+	Message          string   `json:"message"`
+}
+
+// PriceHistoryResponse is the price trend for a route and travel date
+type PriceHistoryResponse struct {
+	Origin      string            `json:"origin"`
+	Destination string            `json:"destination"`
+	Date        string            `json:"date"`
+	Currency    string            `json:"currency,omitempty"`
+	Trend       []PriceTrendPoint `json:"trend"`
+	Summary     PriceSummary      `json:"summary"`
+}
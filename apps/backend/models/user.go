@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// User is a registered account. The password hash never leaves the server.
+type User struct {
+	ID           string    `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// CredentialsRequest is the body for registering and logging in
+type CredentialsRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// AuthResponse is returned after a successful registration or login
+type AuthResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	User      User      `json:"user"`
+}
+
+// SavedSearch is a flight search stored on a user's account
+type SavedSearch struct {
+	ID        string              `json:"id"`
+	UserID    string              `json:"-"`
+	Name      string              `json:"name,omitempty"`
+	Query     FlightSearchRequest `json:"query"`
+	CreatedAt time.Time           `json:"createdAt"`
+}
+
+// SavedSearchRequest is the body for saving a search
+type SavedSearchRequest struct {
+	Name  string              `json:"name,omitempty"`
+	Query FlightSearchRequest `json:"query"`
+}
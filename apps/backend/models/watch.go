@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Threshold crossing directions
+const (
+	CrossedBelow = "below"
+	CrossedAbove = "above"
+)
+
+// maxWatchCrossings caps the crossing history kept on each watch
+const maxWatchCrossings = 50
+
+// PriceWatchRequest is the body for creating or updating a price watch
+type PriceWatchRequest struct {
+	Origin      string  `json:"origin"`
+	Destination string  `json:"destination"`
+	Date        string  `json:"date"`
+	Passengers  int     `json:"passengers"`
+	MaxPrice    float64 `json:"maxPrice"`
+}
+
+// SearchRequest returns the flight search the watch re-runs
+func (r PriceWatchRequest) SearchRequest() FlightSearchRequest {
+	return FlightSearchRequest{
+		Origin:      r.Origin,
+		Destination: r.Destination,
+		Date:        r.Date,
+		Passengers:  r.Passengers,
+	}
+}
+
+// PriceWatch is a user's subscription to the best price on a route and date
+type PriceWatch struct {
+	ID     string `json:"id"`
+	UserID string `json:"userId"`
+	PriceWatchRequest
+	CreatedAt      time.Time            `json:"createdAt"`
+	UpdatedAt      time.Time            `json:"updatedAt"`
+	LastCheckedAt  *time.Time           `json:"lastCheckedAt,omitempty"`
+	LastBestPrice  *float64             `json:"lastBestPrice,omitempty"`
+	LastCurrency   string               `json:"lastCurrency,omitempty"`
+	LastError      string               `json:"lastError,omitempty"`
+	BelowThreshold bool                 `json:"belowThreshold"`
+	Crossings      []PriceWatchCrossing `json:"crossings"`
+}
+
+// PriceWatchCrossing records the best price moving across a watch threshold
+type PriceWatchCrossing struct {
+	At        time.Time `json:"at"`
+	Direction string    `json:"direction"`
+	Price     float64   `json:"price"`
+	Currency  string    `json:"currency"`
+	Threshold float64   `json:"threshold"`
+	FlightID  string    `json:"flightId,omitempty"`
+	Route     []string  `json:"route,omitempty"`
+}
+
+// AddCrossing appends a crossing, dropping the oldest beyond the history cap
+func (w *PriceWatch) AddCrossing(c PriceWatchCrossing) {
+	w.Crossings = append(w.Crossings, c)
+	if len(w.Crossings) > maxWatchCrossings {
+		w.Crossings = w.Crossings[len(w.Crossings)-maxWatchCrossings:]
+	}
+}
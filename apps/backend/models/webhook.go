@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Webhook event types
+const (
+	EventPriceDrop       = "price.drop"
+	EventSearchCompleted = "search.completed"
+	EventPing            = "ping"
+)
+
+// WebhookEventTypes lists the events clients can subscribe to
+var WebhookEventTypes = []string{EventPriceDrop, EventSearchCompleted}
+
+// WebhookRequest is the body for registering a webhook
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+// Webhook is a user's URL subscribed to one or more event types
+type Webhook struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Subscribed reports whether the webhook wants events of the given type
+func (w Webhook) Subscribed(eventType string) bool {
+	if eventType == EventPing {
+		return true
+	}
+	for _, event := range w.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvent is the JSON payload delivered to webhook URLs
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookDelivery records one attempt to deliver an event
+type WebhookDelivery struct {
+	ID          string    `json:"id"`
+	WebhookID   string    `json:"webhookId"`
+	EventID     string    `json:"eventId"`
+	EventType   string    `json:"eventType"`
+	URL         string    `json:"url"`
+	Attempt     int       `json:"attempt"`
+	StatusCode  int       `json:"statusCode,omitempty"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+	DurationMs  int64     `json:"durationMs"`
+	AttemptedAt time.Time `json:"attemptedAt"`
+}
@@ -1,123 +1,94 @@
 package services
 
 import (
-	"encoding/csv"
-	"os"
+	"math"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type Airport struct {
-	CountryCode string `json:"country_code"`
-	RegionName  string `json:"region_name"`
-	IATA        string `json:"iata"`
-	ICAO        string `json:"icao"`
-	AirportName string `json:"airport_name"`
-	Latitude    string `json:"latitude"`
-	Longitude   string `json:"longitude"`
+	CountryCode string  `json:"country_code"`
+	RegionName  string  `json:"region_name"`
+	IATA        string  `json:"iata"`
+	ICAO        string  `json:"icao"`
+	AirportName string  `json:"airport_name"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
 }
 
+// AirportService answers airport lookups from the airport data, which can be
+// reloaded while it serves them
 type AirportService struct {
-	airports map[string]Airport // IATA code -> Airport
+	path     string // airport data file; empty for the embedded data
+	reloadMu sync.Mutex
+	data     atomic.Pointer[airportData]
 }
 
-func NewAirportService() *AirportService {
-	service := &AirportService{
-		airports: make(map[string]Airport),
-	}
-	service.loadAirports()
-	return service
-}
-
-func (as *AirportService) loadAirports() {
-	file, err := os.Open("airports.csv")
-	if err != nil {
-		// Fallback to basic airports if CSV not found
-		as.loadBasicAirports()
-		return
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		as.loadBasicAirports()
-		return
-	}
-
-	// Skip header row
-	for i, record := range records {
-		if i == 0 {
-			continue
-		}
-
-		if len(record) >= 7 && record[2] != "" {
-			airport := Airport{
-				CountryCode: record[0],
-				RegionName:  record[1],
-				IATA:        strings.ToUpper(record[2]),
-				ICAO:        record[3],
-				AirportName: record[4],
-				Latitude:    record[5],
-				Longitude:   record[6],
-			}
-			as.airports[airport.IATA] = airport
-		}
-	}
-}
-
-func (as *AirportService) loadBasicAirports() {
-	// Basic fallback airports
-	basicAirports := []Airport{
-		{"TH", "Bangkok", "BKK", "VTBS", "Bangkok - Suvarnabhumi", "13.6900", "100.7501"},
-		{"TH", "Bangkok", "DMK", "VTBD", "Bangkok - Don Mueang", "13.9126", "100.6067"},
-		{"SG", "Singapore", "SIN", "WSSS", "Singapore - Changi", "1.3644", "103.9915"},
-		{"DE", "Frankfurt", "FRA", "EDDF", "Frankfurt", "50.0264", "8.5431"},
-		{"GB", "London", "LHR", "EGLL", "London - Heathrow", "51.4700", "-0.4543"},
-		{"US", "New York", "JFK", "KJFK", "John F. Kennedy International Airport", "40.6413", "-73.7781"},
-		{"US", "Los Angeles", "LAX", "KLAX", "Los Angeles International Airport", "33.9425", "-118.4081"},
-	}
-
-	for _, airport := range basicAirports {
-		as.airports[airport.IATA] = airport
+// NewAirportService loads the airport data from path, or the data embedded in
+// the binary when path is empty
+func NewAirportService(path string) (*AirportService, error) {
+	service := &AirportService{path: path}
+	if _, err := service.Reload(); err != nil {
+		return nil, err
 	}
+	return service, nil
 }
 
 func (as *AirportService) ValidateAirportCode(code string) bool {
 	code = strings.ToUpper(strings.TrimSpace(code))
-	_, exists := as.airports[code]
+	_, exists := as.data.Load().airports[code]
 	return exists && len(code) == 3
 }
 
 func (as *AirportService) GetAirportInfo(code string) (Airport, bool) {
 	code = strings.ToUpper(strings.TrimSpace(code))
-	airport, exists := as.airports[code]
+	airport, exists := as.data.Load().airports[code]
 	return airport, exists
 }
 
-func (as *AirportService) SearchAirports(query string) []Airport {
-	query = strings.ToLower(query)
-	var results []Airport
+// GetAllAirports returns every airport in IATA code order
+func (as *AirportService) GetAllAirports() []Airport {
+	data := as.data.Load()
+	airports := make([]Airport, 0, len(data.codes))
+	for _, code := range data.codes {
+		airports = append(airports, data.airports[code])
+	}
+	return airports
+}
 
-	for _, airport := range as.airports {
-		if strings.Contains(strings.ToLower(airport.IATA), query) ||
-			strings.Contains(strings.ToLower(airport.AirportName), query) ||
-			strings.Contains(strings.ToLower(airport.RegionName), query) {
-			results = append(results, airport)
-		}
+// NearbyAirport is an airport and its distance from another one
+type NearbyAirport struct {
+	Airport
+	DistanceKm float64 `json:"distance_km"`
+}
 
-		// Limit results to prevent overwhelming response
-		if len(results) >= 20 {
-			break
-		}
+// NearbyAirports returns the airports within radiusKm of the airport with the
+// given code, nearest first, not counting that airport itself
+func (as *AirportService) NearbyAirports(code string, radiusKm float64) []NearbyAirport {
+	data := as.data.Load()
+	center, ok := data.airports[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		return nil
 	}
 
-	return results
+	var nearby []NearbyAirport
+	for _, hit := range data.geo.within(center.Latitude, center.Longitude, radiusKm) {
+		if hit.iata != center.IATA {
+			nearby = append(nearby, NearbyAirport{Airport: data.airports[hit.iata], DistanceKm: hit.distanceKm})
+		}
+	}
+	return nearby
 }
 
-func (as *AirportService) GetAllAirports() []Airport {
-	var airports []Airport
-	for _, airport := range as.airports {
-		airports = append(airports, airport)
-	}
-	return airports
+const earthRadiusKm = 6371.0
+
+// distanceKm is the great-circle distance between two points in degrees
+func distanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
 }
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cheapest-flight-backend/utils"
+)
+
+// embeddedAirports is the airport data the binary ships with, used unless
+// AIRPORTS_FILE names another file
+//
+//go:embed data/iata-icao.csv
+var embeddedAirports []byte
+
+// EmbeddedAirportsSource is the load report source of the embedded data
+const EmbeddedAirportsSource = "embedded"
+
+// airportColumns are the columns an airport data file must have, in any
+// order; others are ignored
+var airportColumns = []string{"country_code", "region_name", "iata", "icao", "airport", "latitude", "longitude"}
+
+// maxReportedRowErrors bounds the row errors kept in a load report, so that
+// loading the wrong file doesn't produce thousands of them
+const maxReportedRowErrors = 50
+
+// airportData is one loaded airport dataset with its indexes. A reload
+// replaces it as a whole, so every lookup sees either the old data or the new.
+type airportData struct {
+	airports map[string]Airport // IATA code -> Airport
+	icao     map[string]string  // ICAO code -> IATA code
+	codes    []string           // IATA codes, sorted
+	index    *searchIndex
+	geo      *geoIndex
+	report   AirportLoadReport
+}
+
+// AirportRowError is a row of an airport data file that was rejected
+type AirportRowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e AirportRowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// AirportLoadReport describes the airport data being served
+type AirportLoadReport struct {
+	Source   string            `json:"source"` // the file loaded, or "embedded"
+	Airports int               `json:"airports"`
+	Skipped  int               `json:"skipped"`          // rows without an IATA code, such as private airfields
+	Rejected int               `json:"rejected"`         // rows with errors, which were left out
+	Errors   []AirportRowError `json:"errors,omitempty"` // the first of those errors
+	LoadedAt time.Time         `json:"loadedAt"`
+}
+
+// Reload reads the airport data again, from AIRPORTS_FILE or the embedded
+// copy, and swaps it in for lookups that start afterwards. Rows with errors
+// are left out and listed in the report. When the data can't be used at all,
+// nothing changes and the error says why.
+func (as *AirportService) Reload() (AirportLoadReport, error) {
+	as.reloadMu.Lock()
+	defer as.reloadMu.Unlock()
+
+	source, content := EmbeddedAirportsSource, embeddedAirports
+	if as.path != "" {
+		var err error
+		if content, err = os.ReadFile(as.path); err != nil {
+			return AirportLoadReport{}, fmt.Errorf("failed to read airport data: %w", err)
+		}
+		source = as.path
+	}
+
+	data, err := parseAirports(bytes.NewReader(content))
+	if err != nil {
+		return AirportLoadReport{}, fmt.Errorf("invalid airport data in %s: %w", source, err)
+	}
+	data.report.Source = source
+	data.report.LoadedAt = time.Now().UTC()
+	data.icao, data.codes = buildDirectory(data.airports)
+	data.index = buildSearchIndex(data.airports)
+	data.geo = buildGeoIndex(data.airports)
+
+	as.data.Store(data)
+	return data.report, nil
+}
+
+// LoadReport describes the airport data currently being served
+func (as *AirportService) LoadReport() AirportLoadReport {
+	return as.data.Load().report
+}
+
+// parseAirports reads an airport data CSV file. The header row names the
+// columns. Rows without an IATA code are skipped; rows that are malformed,
+// have coordinates that aren't numbers in range or repeat an IATA code are
+// rejected and reported by line.
+func parseAirports(r io.Reader) (*airportData, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // checked per row, so one bad row doesn't end the file
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header row: %w", err)
+	}
+	column := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		column[name] = i
+	}
+	for _, name := range airportColumns {
+		if _, ok := column[name]; !ok {
+			return nil, fmt.Errorf("the header row has no %q column", name)
+		}
+	}
+
+	data := &airportData{airports: make(map[string]Airport)}
+	seen := make(map[string]int) // IATA code -> line
+	reject := func(line int, format string, args ...any) {
+		data.report.Rejected++
+		if len(data.report.Errors) < maxReportedRowErrors {
+			data.report.Errors = append(data.report.Errors, AirportRowError{Line: line, Message: fmt.Sprintf(format, args...)})
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			reject(parseErr.StartLine, "%v", parseErr.Err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if len(record) != len(header) {
+			reject(line, "has %d fields, the header has %d", len(record), len(header))
+			continue
+		}
+		field := func(name string) string { return strings.TrimSpace(record[column[name]]) }
+
+		iata := strings.ToUpper(field("iata"))
+		if iata == "" {
+			data.report.Skipped++
+			continue
+		}
+		if !utils.ValidateAirportCode(iata) {
+			reject(line, "IATA code %q is not three letters", iata)
+			continue
+		}
+		if first, ok := seen[iata]; ok {
+			reject(line, "IATA code %s is already used on line %d", iata, first)
+			continue
+		}
+
+		lat, err := parseCoordinate(field("latitude"), 90)
+		if err != nil {
+			reject(line, "latitude: %v", err)
+			continue
+		}
+		lon, err := parseCoordinate(field("longitude"), 180)
+		if err != nil {
+			reject(line, "longitude: %v", err)
+			continue
+		}
+
+		seen[iata] = line
+		data.airports[iata] = Airport{
+			CountryCode: strings.ToUpper(field("country_code")),
+			RegionName:  field("region_name"),
+			IATA:        iata,
+			ICAO:        strings.ToUpper(field("icao")),
+			AirportName: field("airport"),
+			Latitude:    lat,
+			Longitude:   lon,
+		}
+	}
+
+	if len(data.airports) == 0 {
+		return nil, errors.New("no airports could be loaded")
+	}
+	data.report.Airports = len(data.airports)
+	return data, nil
+}
+
+// parseCoordinate parses degrees of latitude or longitude, which must be
+// within ±limit
+func parseCoordinate(value string, limit float64) (float64, error) {
+	degrees, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(degrees) {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	if degrees < -limit || degrees > limit {
+		return 0, fmt.Errorf("%v is outside ±%v", degrees, limit)
+	}
+	return degrees, nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"sort"
+	"strings"
+)
+
+// AirportFilter narrows airport listings and searches. Empty fields match
+// every airport; the others are compared case-insensitively.
+type AirportFilter struct {
+	CountryCode string
+	Region      string
+}
+
+func (f AirportFilter) matches(airport Airport) bool {
+	if f.CountryCode != "" && !strings.EqualFold(f.CountryCode, airport.CountryCode) {
+		return false
+	}
+	if f.Region != "" && !strings.EqualFold(f.Region, airport.RegionName) {
+		return false
+	}
+	return true
+}
+
+// Country is a country in the airport data and how many airports it has
+type Country struct {
+	Code     string `json:"code"`
+	Airports int    `json:"airports"`
+}
+
+// buildDirectory indexes airports by ICAO code and sorts their IATA codes.
+// Where airports share an ICAO code, as EuroAirport Basel-Mulhouse-Freiburg
+// does, the first by IATA code wins.
+func buildDirectory(airports map[string]Airport) (map[string]string, []string) {
+	codes := make([]string, 0, len(airports))
+	for code := range airports {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	icao := make(map[string]string, len(airports))
+	for _, code := range codes {
+		key := strings.ToUpper(strings.TrimSpace(airports[code].ICAO))
+		if _, taken := icao[key]; key != "" && !taken {
+			icao[key] = code
+		}
+	}
+	return icao, codes
+}
+
+// LookupAirport finds an airport by its IATA or ICAO code
+func (as *AirportService) LookupAirport(code string) (Airport, bool) {
+	data := as.data.Load()
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if airport, ok := data.airports[code]; ok {
+		return airport, true
+	}
+	if iata, ok := data.icao[code]; ok {
+		return data.airports[iata], true
+	}
+	return Airport{}, false
+}
+
+// ListAirports returns up to limit airports matching filter in IATA code
+// order, starting after the airport with IATA code after, or from the first
+// one when after is empty. It also returns how many airports match in all and
+// whether there are more after this page.
+func (as *AirportService) ListAirports(filter AirportFilter, after string, limit int) ([]Airport, int, bool) {
+	airports := []Airport{}
+	total := 0
+	more := false
+	after = strings.ToUpper(after)
+	data := as.data.Load()
+	for _, code := range data.codes {
+		airport := data.airports[code]
+		if !filter.matches(airport) {
+			continue
+		}
+		total++
+		if code <= after {
+			continue
+		}
+		if len(airports) == limit {
+			more = true
+			continue
+		}
+		airports = append(airports, airport)
+	}
+	return airports, total, more
+}
+
+// Countries returns every country with airports, by country code, with how
+// many airports each has
+func (as *AirportService) Countries() []Country {
+	counts := make(map[string]int)
+	for _, airport := range as.data.Load().airports {
+		counts[airport.CountryCode]++
+	}
+
+	countries := make([]Country, 0, len(counts))
+	for code, count := range counts {
+		countries = append(countries, Country{Code: code, Airports: count})
+	}
+	sort.Slice(countries, func(i, j int) bool { return countries[i].Code < countries[j].Code })
+	return countries
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"math"
+	"sort"
+)
+
+// geoCellDegrees is the size of a spatial index cell. At the equator a cell
+// is about 111 km across, so a nearby search touches only a few of them.
+const geoCellDegrees = 1.0
+
+// kmPerDegree is the length of a degree of latitude
+const kmPerDegree = earthRadiusKm * math.Pi / 180
+
+// geoIndex buckets airports into cells of
+// geoCellDegrees of latitude and longitude, so that finding the airports
+// around a point only measures the distance to those in nearby cells
+type geoIndex struct {
+	cells map[geoCell][]geoPoint
+}
+
+type geoCell struct {
+	lat, lon int
+}
+
+type geoPoint struct {
+	iata     string
+	lat, lon float64
+}
+
+// geoColumns is the number of cells around a circle of latitude
+var geoColumns = int(math.Ceil(360 / geoCellDegrees))
+
+func cellOf(lat, lon float64) geoCell {
+	return geoCell{lat: latRow(lat), lon: lonColumn(lon)}
+}
+
+func latRow(lat float64) int {
+	return int(math.Floor((lat + 90) / geoCellDegrees))
+}
+
+// lonColumn wraps around the antimeridian, so -180 and 180 share a column
+func lonColumn(lon float64) int {
+	column := int(math.Floor((lon + 180) / geoCellDegrees))
+	return ((column % geoColumns) + geoColumns) % geoColumns
+}
+
+func buildGeoIndex(airports map[string]Airport) *geoIndex {
+	index := &geoIndex{cells: make(map[geoCell][]geoPoint)}
+	for code, airport := range airports {
+		cell := cellOf(airport.Latitude, airport.Longitude)
+		index.cells[cell] = append(index.cells[cell], geoPoint{iata: code, lat: airport.Latitude, lon: airport.Longitude})
+	}
+	return index
+}
+
+// geoHit is an indexed airport and its distance from a point
+type geoHit struct {
+	iata       string
+	distanceKm float64
+}
+
+// within returns the airports within radiusKm of a point, nearest first,
+// ties broken by IATA code
+func (g *geoIndex) within(lat, lon, radiusKm float64) []geoHit {
+	// The cells a circle of that radius can touch: a band of latitude, and
+	// within it a range of longitude that widens towards the poles
+	spanLat := radiusKm / kmPerDegree
+	minLat, maxLat := math.Max(lat-spanLat, -90), math.Min(lat+spanLat, 90)
+	columns := []int{}
+	widest := math.Max(math.Abs(minLat), math.Abs(maxLat))
+	if cosLat := math.Cos(widest * math.Pi / 180); widest < 90 && spanLat/cosLat < 180 {
+		spanLon := spanLat / cosLat
+		first := int(math.Floor((lon - spanLon + 180) / geoCellDegrees))
+		last := int(math.Floor((lon + spanLon + 180) / geoCellDegrees))
+		for column := first; column <= last && column-first < geoColumns; column++ {
+			columns = append(columns, ((column%geoColumns)+geoColumns)%geoColumns)
+		}
+	} else {
+		// The circle reaches a pole, or wraps the whole band
+		for column := 0; column < geoColumns; column++ {
+			columns = append(columns, column)
+		}
+	}
+
+	var hits []geoHit
+	for row := latRow(minLat); row <= latRow(maxLat); row++ {
+		for _, column := range columns {
+			for _, p := range g.cells[geoCell{lat: row, lon: column}] {
+				if distance := distanceKm(lat, lon, p.lat, p.lon); distance <= radiusKm {
+					hits = append(hits, geoHit{iata: p.iata, distanceKm: distance})
+				}
+			}
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].distanceKm != hits[j].distanceKm {
+			return hits[i].distanceKm < hits[j].distanceKm
+		}
+		return hits[i].iata < hits[j].iata
+	})
+	return hits
+}
+
+// NearestAirports returns up to limit airports within radiusKm of a point,
+// nearest first
+func (as *AirportService) NearestAirports(lat, lon, radiusKm float64, limit int) []NearbyAirport {
+	data := as.data.Load()
+	hits := data.geo.within(lat, lon, radiusKm)
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	nearest := make([]NearbyAirport, 0, len(hits))
+	for _, hit := range hits {
+		nearest = append(nearest, NearbyAirport{Airport: data.airports[hit.iata], DistanceKm: hit.distanceKm})
+	}
+	return nearest
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Match quality of an airport search result, best first
+const (
+	matchExactCode = iota // the query is the airport's IATA or ICAO code
+	matchCityCode         // the query is the code of the airport's city, such as LON
+	matchPrefix           // a code or a word of the name or region starts with the query
+	matchSubstring        // a code or a word contains the query
+	matchFuzzy            // a word is within a typo or two of the query
+)
+
+// Query words shorter than these get no typo tolerance, or only one typo
+const (
+	minFuzzyLength   = 4
+	twoTypoMinLength = 8
+)
+
+// maxSearchTermLength keeps pathological names out of the index
+const maxSearchTermLength = 64
+
+// searchIndex maps every lower-cased search term (IATA and ICAO codes, the
+// words of airport names and regions, and the codes and names of the
+// metropolitan areas airports belong to) to the airports it belongs to.
+// Terms are kept sorted so a prefix is a contiguous range.
+type searchIndex struct {
+	terms    []string
+	postings map[string][]posting
+	metro    map[string]int // IATA code of metropolitan area members -> rank within their city, busiest first
+}
+
+// posting is one airport a term belongs to
+type posting struct {
+	iata string
+	code bool // the term is the airport's IATA or ICAO code, or its city's code
+	city bool // the term is its city's code
+}
+
+// buildSearchIndex indexes the airports for SearchAirports
+func buildSearchIndex(airports map[string]Airport) *searchIndex {
+	index := &searchIndex{postings: make(map[string][]posting), metro: make(map[string]int)}
+	add := func(term string, p posting) {
+		if term == "" || len(term) > maxSearchTermLength {
+			return
+		}
+		for _, existing := range index.postings[term] {
+			if existing.iata == p.iata {
+				return // BKK is both an airport and its city; the airport wins
+			}
+		}
+		index.postings[term] = append(index.postings[term], p)
+	}
+
+	codes := make([]string, 0, len(airports))
+	for code := range airports {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes) // postings in a stable order
+
+	for _, code := range codes {
+		airport := airports[code]
+		add(strings.ToLower(airport.IATA), posting{iata: airport.IATA, code: true})
+		add(strings.ToLower(airport.ICAO), posting{iata: airport.IATA, code: true})
+		for _, word := range searchWords(airport.AirportName + " " + airport.RegionName) {
+			add(word, posting{iata: airport.IATA})
+		}
+	}
+
+	// Many airports are named after neither their city nor its code, such
+	// as Suvarnabhumi for Bangkok and Heathrow for London
+	for code, metro := range metroAreas {
+		for rank, iata := range metro.airports {
+			if _, ok := airports[iata]; !ok {
+				continue
+			}
+			index.metro[iata] = rank
+			add(strings.ToLower(code), posting{iata: iata, code: true, city: true})
+			for _, word := range searchWords(metro.name) {
+				add(word, posting{iata: iata})
+			}
+		}
+	}
+
+	index.terms = make([]string, 0, len(index.postings))
+	for term := range index.postings {
+		index.terms = append(index.terms, term)
+	}
+	sort.Strings(index.terms)
+	return index
+}
+
+// searchWords splits text into lower-cased words of letters and digits
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// match returns the best match quality of every airport that has a term
+// matching one query word
+func (idx *searchIndex) match(word string) map[string]int {
+	quality := make(map[string]int)
+	record := func(term string, q int) {
+		for _, p := range idx.postings[term] {
+			if p.code && q > matchPrefix {
+				continue // a code containing or resembling the query is noise
+			}
+			pq := q
+			if p.code && term == word {
+				pq = matchExactCode
+				if p.city {
+					pq = matchCityCode
+				}
+			}
+			if current, ok := quality[p.iata]; !ok || pq < current {
+				quality[p.iata] = pq
+			}
+		}
+	}
+
+	// Prefixes are one contiguous run of the sorted terms
+	start := sort.SearchStrings(idx.terms, word)
+	end := start
+	for end < len(idx.terms) && strings.HasPrefix(idx.terms[end], word) {
+		record(idx.terms[end], matchPrefix)
+		end++
+	}
+
+	// Everything else needs a pass over the distinct terms, which are far
+	// fewer than the airports' names
+	maxTypos := typoBudget(word)
+	for i, term := range idx.terms {
+		if i >= start && i < end {
+			continue
+		}
+		switch {
+		case strings.Contains(term, word):
+			record(term, matchSubstring)
+		case maxTypos > 0 && fuzzyPrefixMatch(word, term, maxTypos):
+			record(term, matchFuzzy)
+		}
+	}
+	return quality
+}
+
+// typoBudget is how many edits a query word may be away from a term
+func typoBudget(word string) int {
+	switch n := len([]rune(word)); {
+	case n >= twoTypoMinLength:
+		return 2
+	case n >= minFuzzyLength:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// fuzzyPrefixMatch reports whether word is within maxTypos edits of term or
+// of a prefix of term, so that partly typed words still match
+func fuzzyPrefixMatch(word, term string, maxTypos int) bool {
+	w, t := []rune(word), []rune(term)
+	if len(t) < len(w)-maxTypos {
+		return false
+	}
+	for n := len(w) - maxTypos; n <= len(w)+maxTypos && n <= len(t); n++ {
+		if n > 0 && editDistance(w, t[:n], maxTypos) <= maxTypos {
+			return true
+		}
+	}
+	return false
+}
+
+// editDistance is the Levenshtein distance between a and b, counting an
+// adjacent transposition as one edit. It stops early once the distance is
+// known to exceed limit, returning limit+1.
+func editDistance(a, b []rune, limit int) int {
+	if d := len(a) - len(b); d > limit || -d > limit {
+		return limit + 1
+	}
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(b)]
+}
+
+// SearchAirports returns airports matching every word of the query, best
+// matches first: exact IATA, ICAO or city codes, then prefixes of codes and
+// of words in the name, region or city, then substrings of words, then near
+// misses within a typo or two. Ties go to the airports of major cities,
+// busiest first, then by name and code, so the order is the same on every
+// call. Only airports matching filter are considered. The page of results
+// from offset is returned with the total number of matches.
+func (as *AirportService) SearchAirports(query string, filter AirportFilter, limit, offset int) ([]Airport, int) {
+	data := as.data.Load()
+	words := searchWords(query)
+	if len(words) == 0 {
+		return []Airport{}, 0
+	}
+
+	// An airport's quality is that of its worst-matching query word
+	var quality map[string]int
+	for _, word := range words {
+		matches := data.index.match(word)
+		if quality == nil {
+			quality = matches
+			continue
+		}
+		for iata, q := range quality {
+			wordQuality, ok := matches[iata]
+			if !ok {
+				delete(quality, iata)
+			} else if wordQuality > q {
+				quality[iata] = wordQuality
+			}
+		}
+	}
+
+	results := make([]Airport, 0, len(quality))
+	for iata := range quality {
+		if airport := data.airports[iata]; filter.matches(airport) {
+			results = append(results, airport)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		qi, qj := quality[results[i].IATA], quality[results[j].IATA]
+		if qi != qj {
+			return qi < qj
+		}
+		ri, mi := data.index.metro[results[i].IATA]
+		rj, mj := data.index.metro[results[j].IATA]
+		if mi != mj {
+			return mi
+		}
+		if ri != rj {
+			return ri < rj
+		}
+		if results[i].AirportName != results[j].AirportName {
+			return results[i].AirportName < results[j].AirportName
+		}
+		return results[i].IATA < results[j].IATA
+	})
+
+	total := len(results)
+	if offset >= total {
+		return []Airport{}, total
+	}
+	results = results[offset:]
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, total
+}
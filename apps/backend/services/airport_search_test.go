@@ -0,0 +1,185 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"", "", 2, 0},
+		{"heath", "heath", 2, 0},
+		{"heath", "heats", 2, 1},
+		{"heath", "heth", 2, 1},
+		{"heath", "heatth", 2, 1},
+		{"heath", "haeth", 2, 1}, // a transposition is one edit
+		{"gatwick", "gatwcik", 2, 1},
+		{"abcd", "badc", 2, 2},
+		{"kitten", "sitting", 3, 3},
+		{"kitten", "sitting", 1, 2}, // stops early at limit+1
+		{"a", "abcd", 1, 2},         // lengths too far apart
+	}
+
+	for _, tt := range tests {
+		if got := editDistance([]rune(tt.a), []rune(tt.b), tt.limit); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyPrefixMatch(t *testing.T) {
+	tests := []struct {
+		word, term string
+		maxTypos   int
+		want       bool
+	}{
+		{"heathrw", "heathrow", 1, true},   // a missing letter
+		{"haeth", "heathrow", 1, true},     // a transposition in a partly typed word
+		{"lndon", "london", 1, true},       // a missing letter in a whole word
+		{"gatwcik", "gatwick", 1, true},    // a transposition in a whole word
+		{"lodnno", "london", 1, false},     // two typos
+		{"lodnno", "london", 2, true},      // within two typos
+		{"heathrow", "heat", 1, false},     // the term is too short
+		{"xyzw", "london", 1, false},       // nothing in common
+		{"stansted", "stanstead", 1, true}, // an extra letter in the term
+	}
+
+	for _, tt := range tests {
+		if got := fuzzyPrefixMatch(tt.word, tt.term, tt.maxTypos); got != tt.want {
+			t.Errorf("fuzzyPrefixMatch(%q, %q, %d) = %v, want %v", tt.word, tt.term, tt.maxTypos, got, tt.want)
+		}
+	}
+}
+
+// newTestAirportService loads a small airport file. LHR and LGW belong to
+// the London metropolitan area, LON; the other codes belong to no city.
+func newTestAirportService(t *testing.T) *AirportService {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "airports.csv")
+	data := `country_code,region_name,iata,icao,airport,latitude,longitude
+GB,Region One,LHR,EGLL,Heathrow Airport,51.47,-0.45
+GB,Region One,LGW,EGKK,Gatwick Airport,51.15,-0.18
+GB,Region Two,LON,XLON,Test Field,52.00,0.00
+IE,Region Three,XLF,XXLF,Longford Airfield,53.72,-7.80
+AU,Region Four,AVV,YMAV,Avalon Airport,-38.03,144.47
+GB,Region Two,XBH,XXBH,Blackheath Strip,51.46,0.01
+GB,Region Two,XHA,XXHA,Haeth Field,51.40,0.02
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("write airports: %v", err)
+	}
+	service, err := NewAirportService(path)
+	if err != nil {
+		t.Fatalf("NewAirportService: %v", err)
+	}
+	return service
+}
+
+func airportCodes(airports []Airport) []string {
+	codes := make([]string, 0, len(airports))
+	for _, a := range airports {
+		codes = append(codes, a.IATA)
+	}
+	return codes
+}
+
+func TestSearchAirportsOrdering(t *testing.T) {
+	service := newTestAirportService(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{
+			// Exact code, then city code with the busiest airport first, then
+			// a word prefix, then a substring
+			name:  "code, city, prefix, substring",
+			query: "lon",
+			want:  []string{"LON", "LHR", "LGW", "XLF", "AVV"},
+		},
+		{
+			name:  "prefix, substring, fuzzy",
+			query: "heath",
+			want:  []string{"LHR", "XBH", "XHA"},
+		},
+		{
+			name:  "exact ICAO code",
+			query: "EGLL",
+			want:  []string{"LHR"},
+		},
+		{
+			// Ties go to city airports by rank, then by name
+			name:  "ties",
+			query: "airport",
+			want:  []string{"LHR", "LGW", "AVV"},
+		},
+		{
+			name:  "every word must match",
+			query: "heathrow airport",
+			want:  []string{"LHR"},
+		},
+		{
+			name:  "codes are not matched fuzzily",
+			query: "xlox",
+			want:  []string{},
+		},
+		{
+			name:  "no words",
+			query: " - ",
+			want:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, total := service.SearchAirports(tt.query, AirportFilter{}, 0, 0)
+			if got := airportCodes(results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SearchAirports(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			if total != len(tt.want) {
+				t.Errorf("SearchAirports(%q) total = %d, want %d", tt.query, total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestSearchAirportsIsStable(t *testing.T) {
+	service := newTestAirportService(t)
+
+	first, _ := service.SearchAirports("a", AirportFilter{}, 0, 0)
+	for i := 0; i < 20; i++ {
+		again, _ := service.SearchAirports("a", AirportFilter{}, 0, 0)
+		if !reflect.DeepEqual(airportCodes(again), airportCodes(first)) {
+			t.Fatalf("call %d returned %v, first call returned %v", i, airportCodes(again), airportCodes(first))
+		}
+	}
+
+	// Pages of the same query line up with the full result
+	var paged []Airport
+	for offset := 0; offset < len(first); offset += 2 {
+		page, total := service.SearchAirports("a", AirportFilter{}, 2, offset)
+		if total != len(first) {
+			t.Fatalf("total = %d, want %d", total, len(first))
+		}
+		paged = append(paged, page...)
+	}
+	if !reflect.DeepEqual(airportCodes(paged), airportCodes(first)) {
+		t.Errorf("paged results %v, want %v", airportCodes(paged), airportCodes(first))
+	}
+}
+
+func TestSearchAirportsFilter(t *testing.T) {
+	service := newTestAirportService(t)
+
+	results, total := service.SearchAirports("airport", AirportFilter{CountryCode: "au"}, 0, 0)
+	if got := airportCodes(results); !reflect.DeepEqual(got, []string{"AVV"}) || total != 1 {
+		t.Errorf("filtered search = %v (total %d), want [AVV]", got, total)
+	}
+}
@@ -1,83 +1,165 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"cheapest-flight-backend/logging"
+	"cheapest-flight-backend/metrics"
 	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/tracing"
 )
 
 type AmadeusService struct {
-	BaseURL      string
-	ClientID     string
-	ClientSecret string
-	AccessToken  string
-	TokenExpiry  time.Time
-	HTTPClient   *http.Client
-	mutex        sync.RWMutex
+	BaseURL    string
+	Currency   string // currency prices are quoted in
+	HTTPClient *http.Client
+	budget     *CallBudget
+
+	credentials    []*credentialSet
+	strategy       string
+	nextCredential atomic.Uint64
 }
 
-func NewAmadeusService(baseURL, clientID, clientSecret string) *AmadeusService {
+// NewAmadeusService creates a client that spreads calls over the credential
+// sets according to strategy (CredentialFailover or CredentialRotate). A set
+// Amadeus rejects is benched and its calls retried with the next set.
+func NewAmadeusService(baseURL string, credentials []AmadeusCredentials, strategy string, budget *CallBudget) *AmadeusService {
+	sets := make([]*credentialSet, len(credentials))
+	for i, creds := range credentials {
+		sets[i] = &credentialSet{AmadeusCredentials: creds, index: i}
+	}
 	return &AmadeusService{
-		BaseURL:      strings.TrimSuffix(baseURL, "/"),
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+		Currency: "USD",
 		HTTPClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		budget:      budget,
+		credentials: sets,
+		strategy:    strategy,
+	}
+}
+
+// Budget returns the call budget shared by all outbound requests
+func (a *AmadeusService) Budget() *CallBudget {
+	return a.budget
+}
+
+// GetAccessToken returns a valid access token from the first credential set
+// that can provide one
+func (a *AmadeusService) GetAccessToken(ctx context.Context) (string, error) {
+	var lastErr error
+	for _, set := range a.credentialOrder() {
+		token, err := a.accessToken(ctx, set)
+		if err == nil {
+			return token, nil
+		}
+		lastErr = err
 	}
+	if lastErr == nil {
+		lastErr = errors.New("no Amadeus credentials configured")
+	}
+	return "", lastErr
 }
 
-// GetAccessToken retrieves or refreshes the access token
-func (a *AmadeusService) GetAccessToken() (string, error) {
-	a.mutex.RLock()
+// accessToken retrieves or refreshes the access token of one credential set
+func (a *AmadeusService) accessToken(ctx context.Context, set *credentialSet) (string, error) {
 	// Check if we have a valid token
-	if a.AccessToken != "" && time.Now().Before(a.TokenExpiry.Add(-5*time.Minute)) {
-		token := a.AccessToken
-		a.mutex.RUnlock()
+	if token, ok := set.cachedToken(); ok {
+		metrics.TokenCache.WithLabelValues("hit").Inc()
+		searchDiagnosticsFromContext(ctx).recordTokenCache(true)
 		return token, nil
 	}
-	a.mutex.RUnlock()
 
 	// Need to get a new token
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
+	set.mu.Lock()
+	defer set.mu.Unlock()
 
 	// Double-check after acquiring write lock
-	if a.AccessToken != "" && time.Now().Before(a.TokenExpiry.Add(-5*time.Minute)) {
-		return a.AccessToken, nil
+	if token, ok := set.validToken(); ok {
+		metrics.TokenCache.WithLabelValues("hit").Inc()
+		searchDiagnosticsFromContext(ctx).recordTokenCache(true)
+		return token, nil
 	}
+	metrics.TokenCache.WithLabelValues("miss").Inc()
+	searchDiagnosticsFromContext(ctx).recordTokenCache(false)
 
+	// Other callers wait on this refresh, so one caller going away mustn't cancel it
+	token, err := a.refreshToken(context.WithoutCancel(ctx), set)
+	if err != nil {
+		metrics.TokenRefreshes.WithLabelValues("failure").Inc()
+		slog.ErrorContext(ctx, "amadeus token refresh failed", "credential", set.index, "error", err)
+		if isCredentialRejected(err) {
+			// Locked already, so bench directly rather than through bench()
+			set.benchedUntil = time.Now().Add(rejectedCredentialCooldown)
+		}
+		return "", err
+	}
+	metrics.TokenRefreshes.WithLabelValues("success").Inc()
+	slog.InfoContext(ctx, "amadeus token refreshed", "credential", set.index, "expires_at", set.tokenExpiry)
+	return token, nil
+}
+
+// rejectedCredentialCooldown is how long a set whose key or secret Amadeus
+// refused is skipped
+const rejectedCredentialCooldown = 5 * time.Minute
+
+// refreshToken fetches a new access token. Callers must hold the set's write
+// lock.
+func (a *AmadeusService) refreshToken(ctx context.Context, set *credentialSet) (string, error) {
 	// Request new token
 	tokenURL := fmt.Sprintf("%s/v1/security/oauth2/token", a.BaseURL)
 
 	data := url.Values{}
 	data.Set("grant_type", "client_credentials")
-	data.Set("client_id", a.ClientID)
-	data.Set("client_secret", a.ClientSecret)
+	data.Set("client_id", set.ClientID)
+	data.Set("client_secret", set.ClientSecret)
+
+	ctx, span := startCallSpan(ctx, metrics.EndpointToken, "POST", tokenURL,
+		attribute.Int("amadeus.credential", set.index),
+	)
+	defer span.End()
 
-	req, err := http.NewRequest("POST", tokenURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return "", fmt.Errorf("failed to create token request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
+	start := time.Now()
 	resp, err := a.HTTPClient.Do(req)
 	if err != nil {
+		metrics.ObserveAmadeusCall(metrics.EndpointToken, 0, time.Since(start).Seconds())
+		recordCallResult(span, 0, err)
 		return "", fmt.Errorf("failed to request token: %w", err)
 	}
 	defer resp.Body.Close()
+	metrics.ObserveAmadeusCall(metrics.EndpointToken, resp.StatusCode, time.Since(start).Seconds())
+	recordCallResult(span, resp.StatusCode, nil)
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+			// Amadeus answers an unknown key or wrong secret with 401 (or 400)
+			return "", rejected(resp.StatusCode, "token request failed with status %d: %s", resp.StatusCode, string(body))
+		}
 		return "", fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
@@ -86,62 +168,215 @@ func (a *AmadeusService) GetAccessToken() (string, error) {
 		return "", fmt.Errorf("failed to decode token response: %w", err)
 	}
 
-	a.AccessToken = tokenResp.AccessToken
-	a.TokenExpiry = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	set.accessToken = tokenResp.AccessToken
+	set.tokenExpiry = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
 
-	return a.AccessToken, nil
+	return set.accessToken, nil
 }
 
-// SearchFlights searches for flights using the Amadeus API
-func (a *AmadeusService) SearchFlights(req models.FlightSearchRequest) (*models.AmadeusFlightResponse, error) {
-	token, err := a.GetAccessToken()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get access token: %w", err)
-	}
-
-	// Build the search URL
-	searchURL := fmt.Sprintf("%s/v2/shopping/flight-offers", a.BaseURL)
-
-	// Prepare query parameters
+// SearchFlights searches for flights using the Amadeus API. Each call is
+// counted against the shared call budget and the search stats in ctx. When
+// Amadeus refuses a credential set, the search is retried with the next one.
+func (a *AmadeusService) SearchFlights(ctx context.Context, req models.FlightSearchRequest) (*models.AmadeusFlightResponse, error) {
 	params := url.Values{}
 	params.Set("originLocationCode", req.Origin)
 	params.Set("destinationLocationCode", req.Destination)
 	params.Set("departureDate", req.Date)
 	params.Set("adults", strconv.Itoa(req.Passengers))
-	params.Set("max", "250")          // Get up to 250 results for better route optimization
-	params.Set("currencyCode", "USD") // Default to USD, could be configurable
+	params.Set("max", "250") // Get up to 250 results for better route optimization
+	params.Set("currencyCode", a.Currency)
+
+	body, err := a.get(ctx, metrics.EndpointFlightOffers, "/v2/shopping/flight-offers", params, req.Origin+"-"+req.Destination)
+	if err != nil {
+		return nil, err
+	}
+
+	var flightResp models.AmadeusFlightResponse
+	if err := json.Unmarshal(body, &flightResp); err != nil {
+		return nil, fmt.Errorf("failed to decode flight response: %w", err)
+	}
 
-	fullURL := fmt.Sprintf("%s?%s", searchURL, params.Encode())
+	return &flightResp, nil
+}
+
+// SearchDestinations asks the Amadeus flight inspiration endpoint for the
+// cheapest destinations from origin departing between from and to
+// (inclusive), priced at most maxPrice. It is budgeted and retried like
+// SearchFlights.
+func (a *AmadeusService) SearchDestinations(ctx context.Context, origin, from, to string, maxPrice float64) (*models.AmadeusDestinationResponse, error) {
+	params := url.Values{}
+	params.Set("origin", origin)
+	params.Set("departureDate", from+","+to)
+	params.Set("oneWay", "true")
+	params.Set("viewBy", "DESTINATION")
+	if maxPrice > 0 {
+		params.Set("maxPrice", strconv.Itoa(int(maxPrice)))
+	}
 
-	httpReq, err := http.NewRequest("GET", fullURL, nil)
+	body, err := a.get(ctx, metrics.EndpointFlightDestinations, "/v1/shopping/flight-destinations", params, origin)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create search request: %w", err)
+		return nil, err
+	}
+
+	var destinations models.AmadeusDestinationResponse
+	if err := json.Unmarshal(body, &destinations); err != nil {
+		return nil, fmt.Errorf("failed to decode destinations response: %w", err)
+	}
+
+	return &destinations, nil
+}
+
+// get makes a GET request to an Amadeus endpoint and returns the body of a
+// successful response, retrying with the next credential set when Amadeus
+// refuses one
+func (a *AmadeusService) get(ctx context.Context, endpoint, path string, params url.Values, route string) ([]byte, error) {
+	fullURL := fmt.Sprintf("%s%s?%s", a.BaseURL, path, params.Encode())
+
+	var lastErr error
+	for attempt, set := range a.credentialOrder() {
+		if attempt > 0 {
+			metrics.CredentialFailovers.Inc()
+			slog.WarnContext(ctx, "retrying amadeus call with the next credentials",
+				"endpoint", endpoint,
+				"credential", set.index,
+				"error", lastErr,
+			)
+		}
+		body, err := a.getWith(ctx, set, endpoint, fullURL, route)
+		if err == nil || !isCredentialRejected(err) {
+			return body, err
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no Amadeus credentials configured")
+	}
+	return nil, lastErr
+}
+
+// getWith makes one GET request with the given credentials. Each attempt is
+// counted against the shared call budget and the search stats in ctx, since
+// Amadeus bills failovers like any other call.
+func (a *AmadeusService) getWith(ctx context.Context, set *credentialSet, endpoint, fullURL, route string) ([]byte, error) {
+	if a.budget != nil {
+		release, err := a.budget.Acquire(ctx)
+		if err != nil {
+			if err == ErrQuotaExhausted {
+				SearchStatsFromContext(ctx).recordRejected()
+			}
+			return nil, err
+		}
+		defer release()
+	}
+
+	token, err := a.accessToken(ctx, set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+	SearchStatsFromContext(ctx).recordCall()
+
+	ctx, span := startCallSpan(ctx, endpoint, "GET", fullURL,
+		attribute.String("route", route),
+		attribute.Int("amadeus.credential", set.index),
+	)
+	defer span.End()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %w", endpoint, err)
 	}
 
 	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
 	httpReq.Header.Set("Content-Type", "application/json")
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		// Amadeus echoes this reference back, tying their logs to ours
+		httpReq.Header.Set("Ama-Client-Ref", requestID)
+	}
 
+	start := time.Now()
 	resp, err := a.HTTPClient.Do(httpReq)
+	elapsed := time.Since(start)
+	searchDiagnosticsFromContext(ctx).recordUpstreamCall(ctx, elapsed)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search flights: %w", err)
+		metrics.ObserveAmadeusCall(endpoint, 0, elapsed.Seconds())
+		recordCallResult(span, 0, err)
+		slog.WarnContext(ctx, "amadeus call failed",
+			"endpoint", endpoint,
+			"route", route,
+			"credential", set.index,
+			"duration_ms", elapsed.Milliseconds(),
+			"error", err,
+		)
+		return nil, fmt.Errorf("%s request failed: %w", endpoint, err)
 	}
 	defer resp.Body.Close()
+	metrics.ObserveAmadeusCall(endpoint, resp.StatusCode, elapsed.Seconds())
+	recordCallResult(span, resp.StatusCode, nil)
+	slog.DebugContext(ctx, "amadeus call",
+		"endpoint", endpoint,
+		"route", route,
+		"credential", set.index,
+		"status", resp.StatusCode,
+		"duration_ms", elapsed.Milliseconds(),
+	)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("flight search failed with status %d: %s", resp.StatusCode, string(body))
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, nil
+	case http.StatusTooManyRequests:
+		// This key's rate limit or quota is spent; give it a rest
+		set.bench(retryAfterHeader(resp.Header, time.Minute))
+		return nil, rejected(resp.StatusCode, "%s request failed with status %d: %s", endpoint, resp.StatusCode, string(body))
+	case http.StatusUnauthorized:
+		// The token was revoked or expired early; fetch a new one next time
+		set.forgetToken()
+		return nil, rejected(resp.StatusCode, "%s request failed with status %d: %s", endpoint, resp.StatusCode, string(body))
+	default:
+		return nil, fmt.Errorf("%s request failed with status %d: %s", endpoint, resp.StatusCode, string(body))
 	}
+}
 
-	var flightResp models.AmadeusFlightResponse
-	if err := json.Unmarshal(body, &flightResp); err != nil {
-		return nil, fmt.Errorf("failed to decode flight response: %w", err)
+// retryAfterHeader parses a Retry-After header given in seconds, falling back
+// to def when it is missing or unparseable
+func retryAfterHeader(h http.Header, def time.Duration) time.Duration {
+	if seconds, err := strconv.Atoi(h.Get("Retry-After")); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
 	}
+	return def
+}
 
-	return &flightResp, nil
+// startCallSpan starts a client span for an outbound Amadeus request
+func startCallSpan(ctx context.Context, endpoint, method, fullURL string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		attribute.String("amadeus.endpoint", endpoint),
+		semconv.HTTPRequestMethodKey.String(method),
+		semconv.URLFull(fullURL),
+	)
+	return tracing.Tracer().Start(ctx, "amadeus "+endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// recordCallResult records the response status, or the transport error when
+// there was no response, on an Amadeus call span
+func recordCallResult(span trace.Span, status int, err error) {
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("status", "error"))
+	case status >= http.StatusBadRequest:
+		span.SetStatus(codes.Error, http.StatusText(status))
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status), attribute.String("status", "error"))
+	default:
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status), attribute.String("status", "ok"))
+	}
 }
 
 // ConvertAmadeusFlights converts Amadeus flight offers to our Flight model
@@ -203,6 +438,7 @@ func (a *AmadeusService) convertSingleFlight(offer models.AmadeusFlightOffer, or
 		Route:       route,
 		BookingURL:  "", // We'll implement booking URLs later
 	}
+	flight.DurationMinutes, _ = parseDurationMinutes(itinerary.Duration)
 
 	return flight
 }
@@ -278,8 +514,49 @@ func (a *AmadeusService) formatDuration(isoDuration string) string {
 	return isoDuration
 }
 
+// parseDurationMinutes parses an ISO 8601 duration such as PT26H10M or
+// P1DT2H into whole minutes
+func parseDurationMinutes(isoDuration string) (int, bool) {
+	rest, ok := strings.CutPrefix(isoDuration, "P")
+	if !ok || rest == "" {
+		return 0, false
+	}
+
+	minutes := 0
+	inTime := false
+	for rest != "" {
+		if rest[0] == 'T' {
+			inTime = true
+			rest = rest[1:]
+			continue
+		}
+		end := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if end <= 0 {
+			return 0, false
+		}
+		n, err := strconv.Atoi(rest[:end])
+		if err != nil {
+			return 0, false
+		}
+		switch unit := rest[end]; {
+		case unit == 'D' && !inTime:
+			minutes += n * 24 * 60
+		case unit == 'H' && inTime:
+			minutes += n * 60
+		case unit == 'M' && inTime:
+			minutes += n
+		case unit == 'S' && inTime:
+			// Seconds don't change the minutes shown
+		default:
+			return 0, false
+		}
+		rest = rest[end+1:]
+	}
+	return minutes, true
+}
+
 // HealthCheck checks if the Amadeus API is accessible
-func (a *AmadeusService) HealthCheck() error {
-	_, err := a.GetAccessToken()
+func (a *AmadeusService) HealthCheck(ctx context.Context) error {
+	_, err := a.GetAccessToken(ctx)
 	return err
 }
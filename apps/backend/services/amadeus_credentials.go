@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Strategies for choosing between several Amadeus credential sets
+const (
+	CredentialFailover = "failover" // always prefer the first usable set
+	CredentialRotate   = "rotate"   // spread calls round-robin over the usable sets
+)
+
+// AmadeusCredentials is one Amadeus API key and secret
+type AmadeusCredentials struct {
+	ClientID     string
+	ClientSecret string
+}
+
+// credentialSet is one set of credentials with its own token cache. A set
+// Amadeus rejects is benched, and skipped while others are usable, until its
+// cooldown passes.
+type credentialSet struct {
+	AmadeusCredentials
+	index int
+
+	mu           sync.RWMutex
+	accessToken  string
+	tokenExpiry  time.Time
+	benchedUntil time.Time
+}
+
+// cachedToken returns the token if it is valid for at least 5 more minutes
+func (c *credentialSet) cachedToken() (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.validToken()
+}
+
+// validToken is cachedToken for callers that hold the lock
+func (c *credentialSet) validToken() (string, bool) {
+	if c.accessToken != "" && time.Now().Before(c.tokenExpiry.Add(-5*time.Minute)) {
+		return c.accessToken, true
+	}
+	return "", false
+}
+
+func (c *credentialSet) forgetToken() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accessToken = ""
+}
+
+func (c *credentialSet) bench(cooldown time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.benchedUntil = time.Now().Add(cooldown)
+}
+
+func (c *credentialSet) availableAt() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.benchedUntil
+}
+
+// credentialOrder returns the sets to try for one call: usable sets first, in
+// the order the strategy prefers, then benched ones soonest available first
+// so a call is still attempted when every set is benched
+func (a *AmadeusService) credentialOrder() []*credentialSet {
+	n := len(a.credentials)
+	start := 0
+	if a.strategy == CredentialRotate && n > 1 {
+		start = int((a.nextCredential.Add(1) - 1) % uint64(n))
+	}
+
+	now := time.Now()
+	usable := make([]*credentialSet, 0, n)
+	var benched []*credentialSet
+	for i := 0; i < n; i++ {
+		set := a.credentials[(start+i)%n]
+		if set.availableAt().After(now) {
+			benched = append(benched, set)
+		} else {
+			usable = append(usable, set)
+		}
+	}
+	sort.SliceStable(benched, func(i, j int) bool {
+		return benched[i].availableAt().Before(benched[j].availableAt())
+	})
+	return append(usable, benched...)
+}
+
+// credentialRejectedError means Amadeus refused a call because of the
+// credentials used, so another set may still succeed
+type credentialRejectedError struct {
+	status int
+	err    error
+}
+
+func (e *credentialRejectedError) Error() string { return e.err.Error() }
+func (e *credentialRejectedError) Unwrap() error { return e.err }
+
+func rejected(status int, format string, args ...interface{}) error {
+	return &credentialRejectedError{status: status, err: fmt.Errorf(format, args...)}
+}
+
+func isCredentialRejected(err error) bool {
+	var rejectedErr *credentialRejectedError
+	return errors.As(err, &rejectedErr)
+}
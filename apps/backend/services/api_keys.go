@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/store"
+	"cheapest-flight-backend/utils"
+)
+
+// API key errors returned to the middleware
+var (
+	ErrInvalidAPIKey      = errors.New("invalid or revoked API key")
+	ErrRateLimited        = errors.New("API key request rate exceeded")
+	ErrSearchQuotaReached = errors.New("API key daily search quota reached")
+)
+
+// apiKeyPrefix marks keys issued by this service so they are easy to spot in
+// logs and secret scanners
+const apiKeyPrefix = "cfk_"
+
+// APIKeyService issues and revokes API keys and meters each key against its
+// own per-minute request limit and daily search quota. Usage is counted in
+// memory, so a restart gives every key a fresh window.
+type APIKeyService struct {
+	store store.APIKeyStore
+
+	mu                   sync.Mutex
+	defaultRatePerMinute int
+	defaultDailySearches int
+	usage                map[string]*keyUsage
+}
+
+// keyUsage tracks one key's fixed one-minute request window and its searches
+// for the current UTC day
+type keyUsage struct {
+	windowStart time.Time
+	requests    int
+	day         string
+	searches    int
+}
+
+func NewAPIKeyService(apiKeyStore store.APIKeyStore, defaultRatePerMinute, defaultDailySearches int) *APIKeyService {
+	return &APIKeyService{
+		store:                apiKeyStore,
+		defaultRatePerMinute: defaultRatePerMinute,
+		defaultDailySearches: defaultDailySearches,
+		usage:                make(map[string]*keyUsage),
+	}
+}
+
+// SetDefaults changes the limits given to keys issued without explicit ones.
+// Existing keys keep theirs.
+func (s *APIKeyService) SetDefaults(ratePerMinute, dailySearches int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultRatePerMinute = ratePerMinute
+	s.defaultDailySearches = dailySearches
+}
+
+// Issue creates a key and returns it in plaintext. This is the only time the
+// key can be seen.
+func (s *APIKeyService) Issue(ctx context.Context, req models.APIKeyRequest) (models.IssuedAPIKey, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return models.IssuedAPIKey{}, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	plaintext := apiKeyPrefix + hex.EncodeToString(secret)
+
+	key := models.APIKey{
+		ID:                utils.NewID(),
+		Name:              req.Name,
+		Prefix:            plaintext[:len(apiKeyPrefix)+6],
+		KeyHash:           hashAPIKey(plaintext),
+		RequestsPerMinute: req.RequestsPerMinute,
+		DailySearches:     req.DailySearches,
+		CreatedAt:         time.Now().UTC(),
+	}
+	s.mu.Lock()
+	if key.RequestsPerMinute == 0 {
+		key.RequestsPerMinute = s.defaultRatePerMinute
+	}
+	if key.DailySearches == 0 {
+		key.DailySearches = s.defaultDailySearches
+	}
+	s.mu.Unlock()
+
+	if err := s.store.SaveAPIKey(ctx, key); err != nil {
+		return models.IssuedAPIKey{}, fmt.Errorf("failed to save API key: %w", err)
+	}
+
+	return models.IssuedAPIKey{APIKey: key, Key: plaintext}, nil
+}
+
+// Revoke permanently disables a key
+func (s *APIKeyService) Revoke(ctx context.Context, id string) (models.APIKey, error) {
+	key, err := s.store.GetAPIKey(ctx, id)
+	if err != nil {
+		return key, err
+	}
+	if key.Revoked() {
+		return key, nil
+	}
+
+	now := time.Now().UTC()
+	key.RevokedAt = &now
+	if err := s.store.SaveAPIKey(ctx, key); err != nil {
+		return key, err
+	}
+
+	s.mu.Lock()
+	delete(s.usage, key.ID)
+	s.mu.Unlock()
+
+	return key, nil
+}
+
+// List returns every key with its usage in the current windows
+func (s *APIKeyService) List(ctx context.Context) ([]models.APIKeyStatus, error) {
+	keys, err := s.store.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	statuses := make([]models.APIKeyStatus, 0, len(keys))
+	for _, key := range keys {
+		statuses = append(statuses, models.APIKeyStatus{
+			APIKey: key,
+			Usage:  s.usageLocked(key, now),
+		})
+	}
+	return statuses, nil
+}
+
+// Authenticate looks up the key a client presented
+func (s *APIKeyService) Authenticate(ctx context.Context, plaintext string) (models.APIKey, error) {
+	key, err := s.store.GetAPIKeyByHash(ctx, hashAPIKey(plaintext))
+	if errors.Is(err, store.ErrNotFound) {
+		return key, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return key, err
+	}
+	if key.Revoked() {
+		return key, ErrInvalidAPIKey
+	}
+	return key, nil
+}
+
+// Consume counts one request against the key, and one search as well when
+// search is true. Nothing is counted if either limit would be exceeded; the
+// returned usage is accurate in both cases.
+func (s *APIKeyService) Consume(key models.APIKey, search bool) (models.APIKeyUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	usage := s.usageLocked(key, now)
+	if usage.RequestsUsed >= usage.RequestLimit {
+		return usage, ErrRateLimited
+	}
+	if search && usage.SearchesUsed >= usage.SearchLimit {
+		return usage, ErrSearchQuotaReached
+	}
+
+	counters := s.usage[key.ID]
+	counters.requests++
+	usage.RequestsUsed++
+	if search {
+		counters.searches++
+		usage.SearchesUsed++
+	}
+	return usage, nil
+}
+
+// usageLocked rolls the key's windows forward and reports its usage. Callers
+// must hold mu.
+func (s *APIKeyService) usageLocked(key models.APIKey, now time.Time) models.APIKeyUsage {
+	counters, exists := s.usage[key.ID]
+	if !exists {
+		counters = &keyUsage{}
+		s.usage[key.ID] = counters
+	}
+
+	window := now.Truncate(time.Minute)
+	if !counters.windowStart.Equal(window) {
+		counters.windowStart = window
+		counters.requests = 0
+	}
+	day := now.Format("2006-01-02")
+	if counters.day != day {
+		counters.day = day
+		counters.searches = 0
+	}
+
+	return models.APIKeyUsage{
+		RequestLimit:  key.RequestsPerMinute,
+		RequestsUsed:  counters.requests,
+		RequestsReset: window.Add(time.Minute),
+		SearchLimit:   key.DailySearches,
+		SearchesUsed:  counters.searches,
+		SearchesReset: now.Truncate(24 * time.Hour).Add(24 * time.Hour),
+	}
+}
+
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/store"
+	"cheapest-flight-backend/utils"
+)
+
+// Authentication errors returned to handlers
+var (
+	ErrEmailTaken         = errors.New("an account with this email already exists")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+)
+
+// tokenIssuer is the JWT "iss" claim for tokens this service signs
+const tokenIssuer = "cheapest-flight-backend"
+
+// AuthService registers users, checks passwords and issues the signed JWTs
+// that identify them on later requests
+type AuthService struct {
+	store  store.UserStore
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewAuthService(userStore store.UserStore, secret string, ttl time.Duration) *AuthService {
+	return &AuthService{
+		store:  userStore,
+		secret: []byte(secret),
+		ttl:    ttl,
+	}
+}
+
+// Register creates an account and returns a token for it
+func (a *AuthService) Register(ctx context.Context, email, password string) (models.AuthResponse, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return models.AuthResponse{}, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := models.User{
+		ID:           utils.NewID(),
+		Email:        NormalizeEmail(email),
+		PasswordHash: string(hash),
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := a.store.CreateUser(ctx, user); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			return models.AuthResponse{}, ErrEmailTaken
+		}
+		return models.AuthResponse{}, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return a.issue(user)
+}
+
+// Login checks the password and returns a fresh token
+func (a *AuthService) Login(ctx context.Context, email, password string) (models.AuthResponse, error) {
+	user, err := a.store.GetUserByEmail(ctx, NormalizeEmail(email))
+	if errors.Is(err, store.ErrNotFound) {
+		// Spend the same time as a real check so unknown emails can't be probed
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return models.AuthResponse{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return models.AuthResponse{}, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return models.AuthResponse{}, ErrInvalidCredentials
+	}
+
+	return a.issue(user)
+}
+
+// Authenticate verifies a token and returns the user it was issued to
+func (a *AuthService) Authenticate(ctx context.Context, token string) (models.User, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return models.User{}, ErrInvalidToken
+	}
+
+	user, err := a.store.GetUser(ctx, claims.Subject)
+	if errors.Is(err, store.ErrNotFound) {
+		return models.User{}, ErrInvalidToken
+	}
+	return user, err
+}
+
+func (a *AuthService) issue(user models.User) (models.AuthResponse, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(a.ttl)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    tokenIssuer,
+		Subject:   user.ID,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	})
+	signed, err := token.SignedString(a.secret)
+	if err != nil {
+		return models.AuthResponse{}, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return models.AuthResponse{
+		Token:     signed,
+		ExpiresAt: expiresAt,
+		User:      user,
+	}, nil
+}
+
+// NormalizeEmail lowercases and trims an email so lookups are case-insensitive
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// dummyHash is compared against when a login names an unknown email
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
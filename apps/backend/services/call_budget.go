@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrQuotaExhausted is returned when the daily Amadeus call quota has been used up
+var ErrQuotaExhausted = errors.New("amadeus daily call quota exhausted")
+
+// CallBudget is a process-wide scheduler for outbound Amadeus calls. It caps
+// the number of calls in flight across all searches and enforces a daily quota
+// that resets at midnight UTC.
+type CallBudget struct {
+	slots      chan struct{}
+	dailyQuota int // 0 means unlimited
+
+	mu       sync.Mutex
+	day      string
+	used     int
+	rejected int
+	inFlight int
+}
+
+// BudgetUsage is a snapshot of the call budget for reporting
+type BudgetUsage struct {
+	Day            string `json:"day"`
+	Used           int    `json:"used"`
+	DailyQuota     int    `json:"daily_quota"`
+	Remaining      int    `json:"remaining"`
+	Rejected       int    `json:"rejected"`
+	InFlight       int    `json:"in_flight"`
+	MaxConcurrency int    `json:"max_concurrency"`
+	ResetsAt       string `json:"resets_at"`
+}
+
+func NewCallBudget(maxConcurrency, dailyQuota int) *CallBudget {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	if dailyQuota < 0 {
+		dailyQuota = 0
+	}
+	return &CallBudget{
+		slots:      make(chan struct{}, maxConcurrency),
+		dailyQuota: dailyQuota,
+		day:        currentDay(),
+	}
+}
+
+// Acquire reserves one call from the daily quota and waits for a free
+// concurrency slot. The returned release function must be called once the
+// call has finished.
+func (b *CallBudget) Acquire(ctx context.Context) (func(), error) {
+	if err := b.reserve(); err != nil {
+		return nil, err
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+	case <-ctx.Done():
+		b.refund()
+		return nil, ctx.Err()
+	}
+
+	b.mu.Lock()
+	b.inFlight++
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			b.inFlight--
+			b.mu.Unlock()
+			<-b.slots
+		})
+	}, nil
+}
+
+// reserve counts a call against today's quota
+func (b *CallBudget) reserve() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rollover()
+	if b.dailyQuota > 0 && b.used >= b.dailyQuota {
+		b.rejected++
+		return ErrQuotaExhausted
+	}
+	b.used++
+	return nil
+}
+
+// refund returns a reserved call that was never made
+func (b *CallBudget) refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.used > 0 {
+		b.used--
+	}
+}
+
+// rollover resets the counters when the UTC day changes. Callers must hold mu.
+func (b *CallBudget) rollover() {
+	if today := currentDay(); today != b.day {
+		b.day = today
+		b.used = 0
+		b.rejected = 0
+	}
+}
+
+// Remaining returns the number of calls left today, or -1 when unlimited
+func (b *CallBudget) Remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rollover()
+	if b.dailyQuota == 0 {
+		return -1
+	}
+	if remaining := b.dailyQuota - b.used; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Exhausted reports whether no calls are left for today
+func (b *CallBudget) Exhausted() bool {
+	return b.Remaining() == 0
+}
+
+// ResetsAt returns the time the daily quota resets
+func (b *CallBudget) ResetsAt() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
+
+// Usage returns a snapshot of the current budget
+func (b *CallBudget) Usage() BudgetUsage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.rollover()
+	remaining := -1
+	if b.dailyQuota > 0 {
+		remaining = b.dailyQuota - b.used
+		if remaining < 0 {
+			remaining = 0
+		}
+	}
+
+	return BudgetUsage{
+		Day:            b.day,
+		Used:           b.used,
+		DailyQuota:     b.dailyQuota,
+		Remaining:      remaining,
+		Rejected:       b.rejected,
+		InFlight:       b.inFlight,
+		MaxConcurrency: cap(b.slots),
+		ResetsAt:       b.ResetsAt().Format(time.RFC3339),
+	}
+}
+
+func currentDay() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+// SearchStats counts the outbound calls made on behalf of a single search
+type SearchStats struct {
+	calls    atomic.Int64
+	rejected atomic.Int64
+}
+
+type searchStatsKey struct{}
+
+// WithSearchStats attaches a fresh call counter to the context
+func WithSearchStats(ctx context.Context) (context.Context, *SearchStats) {
+	stats := &SearchStats{}
+	return context.WithValue(ctx, searchStatsKey{}, stats), stats
+}
+
+// SearchStatsFromContext returns the call counter attached to ctx, if any
+func SearchStatsFromContext(ctx context.Context) *SearchStats {
+	stats, _ := ctx.Value(searchStatsKey{}).(*SearchStats)
+	return stats
+}
+
+// Calls returns the number of outbound calls made for the search
+func (s *SearchStats) Calls() int {
+	return int(s.calls.Load())
+}
+
+// Rejected returns the number of calls refused because the quota ran out
+func (s *SearchStats) Rejected() int {
+	return int(s.rejected.Load())
+}
+
+func (s *SearchStats) recordCall() {
+	if s != nil {
+		s.calls.Add(1)
+	}
+}
+
+func (s *SearchStats) recordRejected() {
+	if s != nil {
+		s.rejected.Add(1)
+	}
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/tracing"
+)
+
+// ExploreService finds the cheapest destinations from an origin. It asks the
+// Amadeus flight inspiration endpoint first; when that is unavailable, as it
+// is for most origins in the Amadeus test environment, it searches a list of
+// candidate destinations instead.
+type ExploreService struct {
+	amadeusService *AmadeusService
+	airportService *AirportService
+	priceHistory   *PriceHistoryService // optional, records fares seen by candidate searches
+	settings       atomic.Pointer[ExploreSettings]
+}
+
+// ExploreSettings control the candidate search used when flight inspiration
+// is unavailable
+type ExploreSettings struct {
+	Destinations []string // candidate destinations, the first kept when MaxCalls runs short
+	SampleDates  int      // departure dates searched per candidate, spread over the window
+	Concurrency  int
+	MaxCalls     int // flight searches one request may make
+}
+
+func NewExploreService(amadeusService *AmadeusService, airportService *AirportService, priceHistory *PriceHistoryService, settings ExploreSettings) *ExploreService {
+	es := &ExploreService{
+		amadeusService: amadeusService,
+		airportService: airportService,
+		priceHistory:   priceHistory,
+	}
+	es.SetSettings(settings)
+	return es
+}
+
+// SetSettings replaces the explore settings. Searches already running keep
+// the settings they started with.
+func (es *ExploreService) SetSettings(settings ExploreSettings) {
+	es.settings.Store(&settings)
+}
+
+// Explore returns the cheapest fare to each destination reachable from the
+// request's origin within its departure window and budget, cheapest first,
+// and the source the fares came from. The request must already be validated
+// and have its defaults applied.
+func (es *ExploreService) Explore(ctx context.Context, req models.ExploreRequest) ([]models.ExploreDestination, string, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "Explore", trace.WithAttributes(
+		attribute.String("explore.origin", req.Origin),
+		attribute.String("explore.departure_from", req.DepartureFrom),
+		attribute.String("explore.departure_to", req.DepartureTo),
+		attribute.Float64("explore.max_price", req.MaxPrice),
+	))
+	defer span.End()
+
+	if budget := es.amadeusService.Budget(); budget != nil && budget.Exhausted() {
+		span.SetStatus(codes.Error, ErrQuotaExhausted.Error())
+		span.SetAttributes(attribute.String("status", "quota_exhausted"))
+		return nil, "", ErrQuotaExhausted
+	}
+
+	source := models.ExploreSourceInspiration
+	destinations, err := es.inspiration(ctx, req)
+	if err != nil && !errors.Is(err, ErrQuotaExhausted) && ctx.Err() == nil {
+		slog.WarnContext(ctx, "flight inspiration unavailable; searching candidate destinations", "origin", req.Origin, "error", err)
+		source = models.ExploreSourceCandidates
+		destinations, err = es.searchCandidates(ctx, req)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("status", "error"), attribute.String("explore.source", source))
+		return nil, source, err
+	}
+
+	for i := range destinations {
+		es.enrich(&destinations[i])
+	}
+	sort.Slice(destinations, func(i, j int) bool {
+		if destinations[i].Price != destinations[j].Price {
+			return destinations[i].Price < destinations[j].Price
+		}
+		return destinations[i].Destination < destinations[j].Destination
+	})
+	if len(destinations) > req.Limit {
+		destinations = destinations[:req.Limit]
+	}
+
+	span.SetAttributes(
+		attribute.String("status", "ok"),
+		attribute.String("explore.source", source),
+		attribute.Int("explore.results", len(destinations)),
+	)
+	return destinations, source, nil
+}
+
+// inspiration asks Amadeus for the cheapest destinations in one call
+func (es *ExploreService) inspiration(ctx context.Context, req models.ExploreRequest) ([]models.ExploreDestination, error) {
+	resp, err := es.amadeusService.SearchDestinations(ctx, req.Origin, req.DepartureFrom, req.DepartureTo, req.MaxPrice)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := resp.Meta.Currency
+	if currency == "" {
+		currency = es.amadeusService.Currency
+	}
+	destinations := make([]models.ExploreDestination, 0, len(resp.Data))
+	for _, result := range resp.Data {
+		price, err := strconv.ParseFloat(result.Price.Total, 64)
+		if err != nil || price > req.MaxPrice || result.Destination == req.Origin {
+			continue
+		}
+		destinations = append(destinations, models.ExploreDestination{
+			Destination:   result.Destination,
+			Price:         price,
+			Currency:      currency,
+			DepartureDate: result.DepartureDate,
+		})
+	}
+	return destinations, nil
+}
+
+// searchCandidates searches the candidate destinations on a sample of the
+// departure dates and keeps the cheapest fare within budget to each. Failed
+// searches are skipped unless every one of them failed.
+func (es *ExploreService) searchCandidates(ctx context.Context, req models.ExploreRequest) ([]models.ExploreDestination, error) {
+	settings := *es.settings.Load()
+	searches := candidateSearches(req.Origin, settings.Destinations,
+		sampleDates(req.DepartureFrom, req.DepartureTo, settings.SampleDates))
+	if settings.MaxCalls > 0 && len(searches) > settings.MaxCalls {
+		slog.InfoContext(ctx, "explore: capping candidate searches", "wanted", len(searches), "max_calls", settings.MaxCalls)
+		searches = searches[:settings.MaxCalls]
+	}
+
+	semaphore := make(chan struct{}, settings.Concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	best := make(map[string]models.ExploreDestination)
+	searched, failed := 0, 0
+	var firstErr error
+
+	for _, search := range searches {
+		wg.Add(1)
+		go func(destination, date string) {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+
+			flight, err := es.cheapestFlight(ctx, req.Origin, destination, date)
+
+			mu.Lock()
+			defer mu.Unlock()
+			searched++
+			if err != nil {
+				failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			if flight == nil || flight.Price > req.MaxPrice {
+				return
+			}
+			if current, ok := best[destination]; ok && current.Price <= flight.Price {
+				return
+			}
+			stops := flight.Stops
+			best[destination] = models.ExploreDestination{
+				Destination:   destination,
+				Price:         flight.Price,
+				Currency:      flight.Currency,
+				DepartureDate: date,
+				Airline:       flight.Airline,
+				Stops:         &stops,
+			}
+		}(search.destination, search.date)
+	}
+	wg.Wait()
+
+	if searched > 0 && failed == searched {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil && searched == 0 {
+		return nil, err
+	}
+	if failed > 0 {
+		slog.WarnContext(ctx, "some candidate destinations could not be searched", "failed", failed, "searched", searched, "error", firstErr)
+	}
+
+	destinations := make([]models.ExploreDestination, 0, len(best))
+	for _, destination := range best {
+		destinations = append(destinations, destination)
+	}
+	return destinations, nil
+}
+
+type candidateSearch struct {
+	destination, date string
+}
+
+// candidateSearches lists the searches for every destination other than the
+// origin, a date at a time, so that a capped list still covers as many
+// destinations as it can
+func candidateSearches(origin string, destinations, dates []string) []candidateSearch {
+	var searches []candidateSearch
+	for _, date := range dates {
+		for _, destination := range destinations {
+			if destination != origin {
+				searches = append(searches, candidateSearch{destination: destination, date: date})
+			}
+		}
+	}
+	return searches
+}
+
+// cheapestFlight returns the cheapest offer for one adult on a route and
+// date, or nil when there is none
+func (es *ExploreService) cheapestFlight(ctx context.Context, origin, destination, date string) (*models.Flight, error) {
+	req := models.FlightSearchRequest{Origin: origin, Destination: destination, Date: date, Passengers: 1}
+	resp, err := es.amadeusService.SearchFlights(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	flights := es.amadeusService.ConvertAmadeusFlights(resp, req)
+	es.priceHistory.Record(ctx, flights)
+
+	var cheapest *models.Flight
+	for i := range flights {
+		if cheapest == nil || flights[i].Price < cheapest.Price {
+			cheapest = &flights[i]
+		}
+	}
+	return cheapest, nil
+}
+
+// enrich fills in what the airport data knows about a destination. City
+// codes returned by flight inspiration, such as PAR, are left as they are.
+func (es *ExploreService) enrich(destination *models.ExploreDestination) {
+	airport, ok := es.airportService.GetAirportInfo(destination.Destination)
+	if !ok {
+		return
+	}
+	destination.Name = airport.AirportName
+	destination.City = airport.RegionName
+	destination.CountryCode = airport.CountryCode
+	destination.Latitude = airport.Latitude
+	destination.Longitude = airport.Longitude
+}
+
+// sampleDates returns up to n dates spread evenly from from to to, both
+// included. Dates are YYYY-MM-DD and from must not be after to.
+func sampleDates(from, to string, n int) []string {
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return []string{from}
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil || n <= 1 || !end.After(start) {
+		return []string{from}
+	}
+
+	days := int(end.Sub(start).Hours() / 24)
+	if days+1 <= n {
+		n = days + 1
+	}
+	dates := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		offset := i * days / (n - 1)
+		dates = append(dates, start.AddDate(0, 0, offset).Format("2006-01-02"))
+	}
+	return dates
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"cheapest-flight-backend/models"
+)
+
+// travelMinutes is a flight's total duration, with unknown durations ranked
+// after every known one
+func travelMinutes(flight models.Flight) int {
+	if flight.DurationMinutes <= 0 {
+		return math.MaxInt
+	}
+	return flight.DurationMinutes
+}
+
+// cheaperFlight orders flights by price, then duration, then stops
+func cheaperFlight(a, b models.Flight) bool {
+	if a.Price != b.Price {
+		return a.Price < b.Price
+	}
+	if travelMinutes(a) != travelMinutes(b) {
+		return travelMinutes(a) < travelMinutes(b)
+	}
+	return a.Stops < b.Stops
+}
+
+// dominates reports whether a is at least as good as b on price, duration
+// and stops, and better on at least one
+func dominates(a, b models.Flight) bool {
+	if a.Price > b.Price || travelMinutes(a) > travelMinutes(b) || a.Stops > b.Stops {
+		return false
+	}
+	return a.Price < b.Price || travelMinutes(a) < travelMinutes(b) || a.Stops < b.Stops
+}
+
+// paretoFrontier returns the flights no other flight dominates, in their
+// original order. A $5-cheaper 30-hour itinerary doesn't push out a direct
+// flight; it sits next to it.
+func paretoFrontier(flights []models.Flight, diag *SearchDiagnostics) []models.Flight {
+	frontier := make([]models.Flight, 0, len(flights))
+	for i, flight := range flights {
+		dominated := false
+		for j, other := range flights {
+			if i != j && dominates(other, flight) {
+				diag.recordDropped(flight, fmt.Sprintf("beaten on price, duration and stops by %s", other.ID))
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			frontier = append(frontier, flight)
+		}
+	}
+	return frontier
+}
+
+// tagFlights marks the cheapest and the fastest flights and the one with the
+// best balance of the two: the lowest sum of its price relative to the
+// cheapest and its duration relative to the fastest. Flights must be sorted
+// with cheaperFlight. A flight can have several tags.
+func tagFlights(flights []models.Flight) {
+	if len(flights) == 0 {
+		return
+	}
+	for i := range flights {
+		flights[i].Tags = nil
+	}
+
+	cheapest := &flights[0]
+	cheapest.Tags = append(cheapest.Tags, models.FlightTagCheapest)
+
+	var fastest *models.Flight
+	for i := range flights {
+		if flights[i].DurationMinutes > 0 && (fastest == nil || travelMinutes(flights[i]) < travelMinutes(*fastest)) {
+			fastest = &flights[i]
+		}
+	}
+	if fastest == nil {
+		return // without durations there is no tradeoff to explain
+	}
+	fastest.Tags = append(fastest.Tags, models.FlightTagFastest)
+
+	if cheapest.Price <= 0 {
+		return
+	}
+	var bestValue *models.Flight
+	bestScore := math.Inf(1)
+	for i := range flights {
+		if flights[i].DurationMinutes <= 0 {
+			continue
+		}
+		score := flights[i].Price/cheapest.Price + float64(flights[i].DurationMinutes)/float64(fastest.DurationMinutes)
+		if score < bestScore {
+			bestValue, bestScore = &flights[i], score
+		}
+	}
+	bestValue.Tags = append(bestValue.Tags, models.FlightTagBestValue)
+}
+
+// trimFrontier keeps at most limit flights, in their original order: the
+// tagged ones, then one flight for each other tradeoff of price, duration and
+// stops, cheapest first, then flights tied with one of those. Ties are only
+// alternatives to a flight already shown, so a direct flight isn't crowded
+// out by ten itineraries with the same price and duration.
+func trimFrontier(flights []models.Flight, limit int, diag *SearchDiagnostics) []models.Flight {
+	if len(flights) <= limit {
+		return flights
+	}
+
+	type tradeoff struct {
+		price   float64
+		minutes int
+		stops   int
+	}
+	priority := make([]int, len(flights)) // 0 tagged, 1 first of its tradeoff, 2 tied
+	seen := make(map[tradeoff]string)     // tradeoff -> ID of its first flight
+	for i, flight := range flights {
+		key := tradeoff{flight.Price, travelMinutes(flight), flight.Stops}
+		_, tied := seen[key]
+		switch {
+		case len(flight.Tags) > 0:
+			priority[i] = 0
+		case tied:
+			priority[i] = 2
+		default:
+			priority[i] = 1
+		}
+		if !tied {
+			seen[key] = flight.ID
+		}
+	}
+
+	keep := make([]bool, len(flights))
+	remaining := limit
+	for level := 0; level <= 2; level++ {
+		for i := range flights {
+			if priority[i] == level && (level == 0 || remaining > 0) {
+				keep[i] = true
+				remaining--
+			}
+		}
+	}
+
+	kept := make([]models.Flight, 0, limit)
+	for i, flight := range flights {
+		if !keep[i] {
+			diag.recordDropped(flight, fmt.Sprintf("one of the best tradeoffs, but outside the top %d", limit))
+			continue
+		}
+		kept = append(kept, flight)
+	}
+	return kept
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"cheapest-flight-backend/models"
+)
+
+// rankedFlight builds a flight with the fields ranking looks at
+func rankedFlight(id string, price float64, minutes, stops int, tags ...string) models.Flight {
+	return models.Flight{ID: id, Price: price, DurationMinutes: minutes, Stops: stops, Tags: tags}
+}
+
+func flightIDs(flights []models.Flight) []string {
+	ids := make([]string, 0, len(flights))
+	for _, f := range flights {
+		ids = append(ids, f.ID)
+	}
+	return ids
+}
+
+func TestParetoFrontier(t *testing.T) {
+	tests := []struct {
+		name    string
+		flights []models.Flight
+		want    []string
+	}{
+		{
+			name: "no flights",
+			want: []string{},
+		},
+		{
+			name: "dearer flight with the same duration and stops is dropped",
+			flights: []models.Flight{
+				rankedFlight("dear", 120, 300, 0),
+				rankedFlight("cheap", 100, 300, 0),
+			},
+			want: []string{"cheap"},
+		},
+		{
+			name: "identical flights do not dominate each other",
+			flights: []models.Flight{
+				rankedFlight("a", 100, 300, 0),
+				rankedFlight("b", 100, 300, 0),
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "fewer stops breaks a price and duration tie",
+			flights: []models.Flight{
+				rankedFlight("one-stop", 100, 300, 1),
+				rankedFlight("direct", 100, 300, 0),
+			},
+			want: []string{"direct"},
+		},
+		{
+			name: "tradeoffs are kept in their original order",
+			flights: []models.Flight{
+				rankedFlight("fast", 300, 300, 0),
+				rankedFlight("slow", 100, 900, 2),
+				rankedFlight("middle", 200, 500, 1),
+			},
+			want: []string{"fast", "slow", "middle"},
+		},
+		{
+			name: "unknown duration loses to a known one at the same price",
+			flights: []models.Flight{
+				rankedFlight("unknown", 100, 0, 0),
+				rankedFlight("known", 100, 300, 0),
+			},
+			want: []string{"known"},
+		},
+		{
+			name: "cheaper flight with an unknown duration is still a tradeoff",
+			flights: []models.Flight{
+				rankedFlight("unknown", 90, 0, 0),
+				rankedFlight("known", 100, 300, 0),
+			},
+			want: []string{"unknown", "known"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := flightIDs(paretoFrontier(tt.flights, nil))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paretoFrontier = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParetoFrontierRecordsDroppedFlights(t *testing.T) {
+	diag := &SearchDiagnostics{}
+	paretoFrontier([]models.Flight{
+		rankedFlight("dear", 120, 300, 0),
+		rankedFlight("cheap", 100, 300, 0),
+	}, diag)
+
+	if len(diag.report.Dropped) != 1 || diag.report.Dropped[0].ID != "dear" {
+		t.Errorf("dropped = %+v, want only dear", diag.report.Dropped)
+	}
+}
+
+func TestCheaperFlightRanksUnknownDurationsLast(t *testing.T) {
+	known := rankedFlight("known", 100, 900, 2)
+	unknown := rankedFlight("unknown", 100, 0, 0)
+	if !cheaperFlight(known, unknown) || cheaperFlight(unknown, known) {
+		t.Error("a flight with an unknown duration should rank after one with the same price and a known duration")
+	}
+}
+
+func TestTagFlights(t *testing.T) {
+	tests := []struct {
+		name    string
+		flights []models.Flight // sorted with cheaperFlight
+		want    map[string][]string
+	}{
+		{
+			name: "no flights",
+			want: map[string][]string{},
+		},
+		{
+			name: "one flight gets every tag",
+			flights: []models.Flight{
+				rankedFlight("only", 100, 300, 0),
+			},
+			want: map[string][]string{
+				"only": {models.FlightTagCheapest, models.FlightTagFastest, models.FlightTagBestValue},
+			},
+		},
+		{
+			name: "best value balances price and duration",
+			flights: []models.Flight{
+				rankedFlight("cheap", 100, 900, 2),
+				rankedFlight("balanced", 150, 400, 1),
+				rankedFlight("fast", 400, 300, 0),
+			},
+			want: map[string][]string{
+				"cheap":    {models.FlightTagCheapest},
+				"balanced": {models.FlightTagBestValue},
+				"fast":     {models.FlightTagFastest},
+			},
+		},
+		{
+			name: "no flight has a duration",
+			flights: []models.Flight{
+				rankedFlight("cheap", 100, 0, 0),
+				rankedFlight("dear", 200, 0, 0),
+			},
+			want: map[string][]string{
+				"cheap": {models.FlightTagCheapest},
+			},
+		},
+		{
+			name: "cheapest flight has no duration",
+			flights: []models.Flight{
+				rankedFlight("cheap", 100, 0, 0),
+				rankedFlight("timed", 200, 300, 0),
+			},
+			want: map[string][]string{
+				"cheap": {models.FlightTagCheapest},
+				"timed": {models.FlightTagFastest, models.FlightTagBestValue},
+			},
+		},
+		{
+			name: "stale tags are cleared",
+			flights: []models.Flight{
+				rankedFlight("cheap", 100, 600, 0),
+				rankedFlight("fast", 300, 120, 0, models.FlightTagCheapest),
+			},
+			want: map[string][]string{
+				"cheap": {models.FlightTagCheapest},
+				"fast":  {models.FlightTagFastest, models.FlightTagBestValue},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tagFlights(tt.flights)
+			for _, flight := range tt.flights {
+				if want := tt.want[flight.ID]; !reflect.DeepEqual(flight.Tags, want) {
+					t.Errorf("%s tags = %v, want %v", flight.ID, flight.Tags, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTrimFrontier(t *testing.T) {
+	tests := []struct {
+		name    string
+		flights []models.Flight
+		limit   int
+		want    []string
+	}{
+		{
+			name: "within the limit",
+			flights: []models.Flight{
+				rankedFlight("a", 100, 300, 0),
+				rankedFlight("b", 100, 300, 0),
+			},
+			limit: 2,
+			want:  []string{"a", "b"},
+		},
+		{
+			name: "first flights of each tradeoff fill the limit in order",
+			flights: []models.Flight{
+				rankedFlight("a", 100, 900, 2),
+				rankedFlight("b", 150, 600, 1),
+				rankedFlight("c", 200, 400, 1),
+				rankedFlight("d", 300, 300, 0),
+			},
+			limit: 2,
+			want:  []string{"a", "b"},
+		},
+		{
+			name: "ties make way for other tradeoffs",
+			flights: []models.Flight{
+				rankedFlight("direct", 100, 300, 0),
+				rankedFlight("same-direct", 100, 300, 0),
+				rankedFlight("other", 80, 600, 1),
+			},
+			limit: 2,
+			want:  []string{"direct", "other"},
+		},
+		{
+			name: "tagged flights are kept beyond the limit",
+			flights: []models.Flight{
+				rankedFlight("untagged", 90, 900, 2),
+				rankedFlight("cheapest", 100, 600, 1, models.FlightTagCheapest),
+				rankedFlight("value", 150, 400, 1, models.FlightTagBestValue),
+				rankedFlight("fastest", 300, 300, 0, models.FlightTagFastest),
+			},
+			limit: 2,
+			want:  []string{"cheapest", "value", "fastest"},
+		},
+		{
+			name: "tagged flights come before untagged ones",
+			flights: []models.Flight{
+				rankedFlight("untagged", 90, 900, 2),
+				rankedFlight("tagged", 300, 300, 0, models.FlightTagFastest),
+			},
+			limit: 1,
+			want:  []string{"tagged"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := flightIDs(trimFrontier(tt.flights, tt.limit, nil))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("trimFrontier = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package services
+
+import "strings"
+
+// metroArea is an IATA metropolitan area: a city with several airports
+type metroArea struct {
+	name     string
+	airports []string // busiest first
+}
+
+// metroAreas maps IATA metropolitan area (city) codes to their member
+// airports. Some city codes, such as BKK and IST, are also the code of one
+// of their airports; searches for them cover the whole city, as they do with
+// airlines and travel agents.
+var metroAreas = map[string]metroArea{
+	"BKK": {"Bangkok", []string{"BKK", "DMK"}},
+	"BJS": {"Beijing", []string{"PEK", "PKX"}},
+	"BUE": {"Buenos Aires", []string{"EZE", "AEP"}},
+	"CHI": {"Chicago", []string{"ORD", "MDW"}},
+	"DXB": {"Dubai", []string{"DXB", "DWC"}},
+	"IST": {"Istanbul", []string{"IST", "SAW"}},
+	"JKT": {"Jakarta", []string{"CGK", "HLP"}},
+	"LON": {"London", []string{"LHR", "LGW", "STN", "LTN", "LCY", "SEN"}},
+	"MIL": {"Milan", []string{"MXP", "LIN", "BGY"}},
+	"MOW": {"Moscow", []string{"SVO", "DME", "VKO"}},
+	"NYC": {"New York", []string{"JFK", "EWR", "LGA"}},
+	"OSA": {"Osaka", []string{"KIX", "ITM"}},
+	"PAR": {"Paris", []string{"CDG", "ORY", "BVA"}},
+	"RIO": {"Rio de Janeiro", []string{"GIG", "SDU"}},
+	"ROM": {"Rome", []string{"FCO", "CIA"}},
+	"SAO": {"Sao Paulo", []string{"GRU", "CGH", "VCP"}},
+	"SEL": {"Seoul", []string{"ICN", "GMP"}},
+	"SHA": {"Shanghai", []string{"PVG", "SHA"}},
+	"STO": {"Stockholm", []string{"ARN", "BMA", "NYO"}},
+	"TYO": {"Tokyo", []string{"HND", "NRT"}},
+	"WAS": {"Washington", []string{"IAD", "DCA", "BWI"}},
+	"YTO": {"Toronto", []string{"YYZ", "YTZ"}},
+}
+
+// MetroAirports returns the member airports of a metropolitan area code, or
+// false when code isn't one
+func (as *AirportService) MetroAirports(code string) ([]string, bool) {
+	metro, ok := metroAreas[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		return nil, false
+	}
+	return append([]string(nil), metro.airports...), true
+}
+
+// ValidateLocationCode reports whether code is a known airport or a
+// metropolitan area code
+func (as *AirportService) ValidateLocationCode(code string) bool {
+	if _, ok := as.MetroAirports(code); ok {
+		return true
+	}
+	return as.ValidateAirportCode(code)
+}
+
+// LocationAirports returns the airports a location code stands for: the
+// members of a metropolitan area, or the airport itself
+func (as *AirportService) LocationAirports(code string) []string {
+	if airports, ok := as.MetroAirports(code); ok {
+		return airports
+	}
+	return []string{strings.ToUpper(strings.TrimSpace(code))}
+}
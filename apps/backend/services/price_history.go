@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/store"
+)
+
+// minHistoryDays is the number of earlier search days needed before today's
+// price can be judged
+const minHistoryDays = 3
+
+// PriceHistoryService records every fare returned by Amadeus and summarizes
+// how prices on a route have moved over time
+type PriceHistoryService struct {
+	store store.PriceStore
+}
+
+func NewPriceHistoryService(priceStore store.PriceStore) *PriceHistoryService {
+	return &PriceHistoryService{store: priceStore}
+}
+
+// Record stores the flights as observed fares. Failures are logged rather
+// than returned so they never break a search.
+func (p *PriceHistoryService) Record(ctx context.Context, flights []models.Flight) {
+	if p == nil || len(flights) == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+	observations := make([]models.PriceObservation, 0, len(flights))
+	for _, flight := range flights {
+		observations = append(observations, models.PriceObservation{
+			Origin:      flight.Origin,
+			Destination: flight.Destination,
+			TravelDate:  flight.Date,
+			ObservedAt:  now,
+			Price:       flight.Price,
+			Currency:    flight.Currency,
+			Carrier:     flight.Airline,
+			Stops:       flight.Stops,
+		})
+	}
+
+	if err := p.store.RecordPrices(ctx, observations); err != nil {
+		slog.WarnContext(ctx, "price history: failed to record fares", "fares", len(observations), "error", err)
+	}
+}
+
+// History returns the daily price trend for a route and travel date, and
+// whether today's best price is low, typical or high compared with it
+func (p *PriceHistoryService) History(ctx context.Context, origin, destination, date string) (models.PriceHistoryResponse, error) {
+	observations, err := p.store.PriceHistory(ctx, origin, destination, date)
+	if err != nil {
+		return models.PriceHistoryResponse{}, fmt.Errorf("failed to load price history: %w", err)
+	}
+
+	response := models.PriceHistoryResponse{
+		Origin:      origin,
+		Destination: destination,
+		Date:        date,
+		Trend:       []models.PriceTrendPoint{},
+	}
+
+	// Only compare like with like when fares came back in several currencies
+	response.Currency = dominantCurrency(observations)
+	byDay := make(map[string][]float64)
+	for _, obs := range observations {
+		if obs.Currency != response.Currency {
+			continue
+		}
+		day := obs.ObservedAt.UTC().Format("2006-01-02")
+		byDay[day] = append(byDay[day], obs.Price)
+	}
+
+	days := make([]string, 0, len(byDay))
+	for day := range byDay {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	for _, day := range days {
+		prices := byDay[day]
+		point := models.PriceTrendPoint{
+			Day:          day,
+			MinPrice:     prices[0],
+			MaxPrice:     prices[0],
+			Observations: len(prices),
+		}
+		sum := 0.0
+		for _, price := range prices {
+			point.MinPrice = math.Min(point.MinPrice, price)
+			point.MaxPrice = math.Max(point.MaxPrice, price)
+			sum += price
+		}
+		point.AvgPrice = math.Round(sum/float64(len(prices))*100) / 100
+		response.Trend = append(response.Trend, point)
+	}
+
+	response.Summary = summarizePrices(response.Trend, time.Now().UTC().Format("2006-01-02"))
+	return response, nil
+}
+
+// summarizePrices judges today's best price against the best price of each
+// earlier day: bottom quartile is low, top quartile is high
+func summarizePrices(trend []models.PriceTrendPoint, today string) models.PriceSummary {
+	var todayBest *float64
+	var earlier []float64
+	for _, point := range trend {
+		best := point.MinPrice
+		if point.Day == today {
+			todayBest = &best
+		} else {
+			earlier = append(earlier, best)
+		}
+	}
+
+	summary := models.PriceSummary{
+		Verdict:    models.PriceUnknown,
+		TodayBest:  todayBest,
+		SampleDays: len(earlier),
+	}
+
+	if len(earlier) > 0 {
+		sort.Float64s(earlier)
+		low, high := earlier[0], earlier[len(earlier)-1]
+		median := percentile(earlier, 0.5)
+		summary.HistoricalLow = &low
+		summary.HistoricalMedian = &median
+		summary.HistoricalHigh = &high
+	}
+
+	switch {
+	case todayBest == nil:
+		summary.Message = "No prices observed today for this route yet"
+	case len(earlier) < minHistoryDays:
+		summary.Message = fmt.Sprintf("Not enough history yet: need prices from at least %d earlier days", minHistoryDays)
+	case *todayBest <= percentile(earlier, 0.25):
+		summary.Verdict = models.PriceLow
+		summary.Message = "Today's best price is low for this route"
+	case *todayBest >= percentile(earlier, 0.75):
+		summary.Verdict = models.PriceHigh
+		summary.Message = "Today's best price is high for this route"
+	default:
+		summary.Verdict = models.PriceTypical
+		summary.Message = "Today's best price is typical for this route"
+	}
+
+	return summary
+}
+
+// percentile returns the p-th percentile of sorted values by linear
+// interpolation
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	weight := rank - float64(lower)
+	return sorted[lower]*(1-weight) + sorted[upper]*weight
+}
+
+// dominantCurrency returns the most frequently observed currency
+func dominantCurrency(observations []models.PriceObservation) string {
+	counts := make(map[string]int)
+	best := ""
+	for _, obs := range observations {
+		counts[obs.Currency]++
+		if counts[obs.Currency] > counts[best] || (counts[obs.Currency] == counts[best] && obs.Currency < best) {
+			best = obs.Currency
+		}
+	}
+	return best
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token-bucket limiter keyed by client. Each client's bucket
+// holds up to burst tokens and refills at a steady rate; a request spends as
+// many tokens as it costs, so expensive endpoints drain the bucket faster.
+type RateLimiter struct {
+	mu         sync.Mutex
+	burst      float64
+	perSecond  float64
+	searchCost int
+	idleTTL    time.Duration
+	buckets    map[string]*bucket
+}
+
+// RateLimits configure a RateLimiter. Searches spend SearchCost tokens from a
+// bucket of Burst that refills at PerMinute; 0 per minute disables limiting.
+type RateLimits struct {
+	PerMinute  int
+	Burst      int
+	SearchCost int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateDecision is the outcome of a rate limit check
+type RateDecision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // time until the request would be allowed
+	ResetAfter time.Duration // time until the bucket is full again
+}
+
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	l := &RateLimiter{buckets: make(map[string]*bucket)}
+	l.SetLimits(limits)
+	return l
+}
+
+// SetLimits changes the limits for every client. Buckets fuller than the new
+// burst are trimmed to it.
+func (l *RateLimiter) SetLimits(limits RateLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.burst = float64(limits.Burst)
+	l.perSecond = float64(limits.PerMinute) / 60
+	l.searchCost = limits.SearchCost
+	l.idleTTL = time.Minute
+	if l.perSecond > 0 {
+		// An idle bucket that has refilled completely is the same as no bucket
+		l.idleTTL += time.Duration(l.burst / l.perSecond * float64(time.Second))
+	}
+	for _, b := range l.buckets {
+		b.tokens = math.Min(b.tokens, l.burst)
+	}
+}
+
+// Enabled reports whether requests are being limited at all
+func (l *RateLimiter) Enabled() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.perSecond > 0
+}
+
+// SearchCost returns the number of tokens a search spends
+func (l *RateLimiter) SearchCost() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.searchCost
+}
+
+// Start launches the janitor that forgets idle clients. It stops when ctx is
+// cancelled.
+func (l *RateLimiter) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(5 * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				l.mu.Lock()
+				for key, b := range l.buckets {
+					if now.Sub(b.last) > l.idleTTL {
+						delete(l.buckets, key)
+					}
+				}
+				l.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// Allow spends cost tokens from the client's bucket if it has enough.
+// Nothing is spent when the request is refused.
+func (l *RateLimiter) Allow(key string, cost int) RateDecision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.perSecond)
+	b.last = now
+
+	need := math.Min(float64(cost), l.burst)
+	decision := RateDecision{Limit: int(l.burst)}
+	if b.tokens >= need {
+		b.tokens -= need
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = l.refillTime(need - b.tokens)
+	}
+	decision.Remaining = int(math.Floor(b.tokens))
+	decision.ResetAfter = l.refillTime(l.burst - b.tokens)
+	return decision
+}
+
+func (l *RateLimiter) refillTime(tokens float64) time.Duration {
+	return time.Duration(tokens / l.perSecond * float64(time.Second))
+}
@@ -3,130 +3,358 @@ package services
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"math"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"cheapest-flight-backend/metrics"
 	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/tracing"
 )
 
 type RouteOptimizer struct {
 	amadeusService *AmadeusService
-	hubAirports    map[string][]string // Region -> list of hub airports
+	airportService *AirportService
+	priceHistory   *PriceHistoryService // optional, records every fare seen
+	settings       atomic.Pointer[SearchSettings]
+}
+
+// SearchSettings control how widely the optimizer looks for connections and
+// how many results it keeps
+type SearchSettings struct {
+	HubAirports        map[string][]string // Region -> list of hub airports for 1-stop routes
+	MajorHubs          []string            // Candidates for 2-stop routes, most important first
+	MaxRelevantHubs    int                 // 1-stop hubs tried per search
+	MaxTwoStopHubs     int                 // Major hubs combined pairwise for 2-stop routes
+	OneStopConcurrency int
+	TwoStopConcurrency int
+	TopResults         int
+	MaxNearbyAirports  int // alternates searched at each end when a nearby radius is given
 }
 
-func NewRouteOptimizer(amadeusService *AmadeusService) *RouteOptimizer {
-	return &RouteOptimizer{
+func NewRouteOptimizer(amadeusService *AmadeusService, airportService *AirportService, priceHistory *PriceHistoryService, settings SearchSettings) *RouteOptimizer {
+	ro := &RouteOptimizer{
 		amadeusService: amadeusService,
-		hubAirports:    initializeHubAirports(),
+		airportService: airportService,
+		priceHistory:   priceHistory,
 	}
+	ro.SetSettings(settings)
+	return ro
 }
 
-// initializeHubAirports initializes major hub airports by region
-func initializeHubAirports() map[string][]string {
-	return map[string][]string{
-		"north_america": {
-			"JFK", "LAX", "ORD", "DFW", "ATL", "DEN", "SFO", "LAS", "SEA", "MIA",
-			"BOS", "IAH", "PHX", "CLT", "MCO", "MSP", "DTW", "PHL", "LGA", "BWI",
-		},
-		"europe": {
-			"LHR", "CDG", "FRA", "AMS", "MAD", "FCO", "MUC", "ZUR", "VIE", "CPH",
-			"ARN", "HEL", "OSL", "LIS", "ATH", "IST", "SVO", "WAW", "PRG", "BUD",
-		},
-		"asia_pacific": {
-			"NRT", "ICN", "PVG", "PEK", "HKG", "SIN", "BKK", "KUL", "CGK", "MNL",
-			"TPE", "CAN", "DEL", "BOM", "SYD", "MEL", "DXB", "DOH", "KWI", "CAI",
-		},
-		"middle_east_africa": {
-			"DXB", "DOH", "AUH", "KWI", "CAI", "JNB", "CPT", "NBO", "ADD", "DAR",
-			"LOS", "ACC", "CAS", "TUN", "ALG", "RUH", "JED", "AMM", "BEY", "BAH",
-		},
-		"south_america": {
-			"GRU", "EZE", "SCL", "BOG", "LIM", "CWB", "FOR", "GIG", "BSB", "MAO",
-			"UIO", "MVD", "ASU", "CCS", "GEO", "PBM", "BEL", "STM", "CGB", "THE",
-		},
-	}
+// SetSettings replaces the search settings. Searches already running keep
+// the settings they started with.
+func (ro *RouteOptimizer) SetSettings(settings SearchSettings) {
+	ro.settings.Store(&settings)
 }
 
-// OptimizeRoutes finds the cheapest routes with up to 3 stops
+// Settings returns the current search settings
+func (ro *RouteOptimizer) Settings() SearchSettings {
+	return *ro.settings.Load()
+}
+
+// Search branches run by OptimizeRoutes
+const (
+	BranchDirect  = "direct"
+	BranchOneStop = "one_stop"
+	BranchTwoStop = "two_stop"
+)
+
+// BranchResult is the outcome of one search branch
+type BranchResult struct {
+	Branch    string
+	Flights   []models.Flight
+	Err       error
+	Completed int // branches finished so far, including this one
+	Total     int
+}
+
+// BranchObserver is called once per branch as it completes. Calls are
+// serialized, so observers do not need their own locking.
+type BranchObserver func(BranchResult)
+
+// OptimizeRoutes finds the cheapest routes with up to 3 stops, between every
+// airport of the requested cities and any nearby alternates the request asks
+// for. Searches are
+// refused outright once the daily call budget is exhausted; if it runs out
+// mid-search the branches that still succeeded are returned.
 func (ro *RouteOptimizer) OptimizeRoutes(ctx context.Context, req models.FlightSearchRequest) ([]models.Flight, error) {
+	return ro.OptimizeRoutesWithProgress(ctx, req, nil)
+}
+
+// OptimizeRoutesWithProgress runs the same search as OptimizeRoutes and
+// reports each branch to onBranch as soon as it finishes
+func (ro *RouteOptimizer) OptimizeRoutesWithProgress(ctx context.Context, req models.FlightSearchRequest, onBranch BranchObserver) ([]models.Flight, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "OptimizeRoutes", trace.WithAttributes(
+		attribute.String("route", req.Origin+"-"+req.Destination),
+		attribute.String("search.date", req.Date),
+		attribute.Int("search.passengers", req.Passengers),
+	))
+	defer span.End()
+
+	if budget := ro.amadeusService.Budget(); budget != nil && budget.Exhausted() {
+		span.SetStatus(codes.Error, ErrQuotaExhausted.Error())
+		span.SetAttributes(attribute.String("status", "quota_exhausted"))
+		return nil, ErrQuotaExhausted
+	}
+
+	// Callers that don't track stats themselves still get fan-out metrics
+	stats := SearchStatsFromContext(ctx)
+	if stats == nil {
+		ctx, stats = WithSearchStats(ctx)
+	}
+	diag := searchDiagnosticsFromContext(ctx)
+
+	pairs := ro.airportPairs(ro.Settings(), req)
+	diag.recordAirportPairs(pairs)
+	span.SetAttributes(attribute.Int("search.airport_pairs", len(pairs)))
+
+	branches := []struct {
+		name   string
+		search func(context.Context, models.FlightSearchRequest) ([]models.Flight, error)
+	}{
+		{BranchDirect, ro.searchDirectFlights},  // 1. Direct flights
+		{BranchOneStop, ro.searchOneStopRoutes}, // 2. 1-stop routes through major hubs
+		{BranchTwoStop, ro.searchTwoStopRoutes}, // 3. 2-stop routes (more complex, limited hubs)
+	}
+
 	var allFlights []models.Flight
 	var wg sync.WaitGroup
-	var mu sync.Mutex
-	errors := make(chan error, 10)
 
-	// Channel to collect flights from all goroutines
-	flightChan := make(chan []models.Flight, 10)
+	// Channel to collect results from all goroutines
+	results := make(chan BranchResult, len(branches))
 
-	// 1. Search direct flights
-	wg.Add(1)
+	for _, branch := range branches {
+		wg.Add(1)
+		go func(name string, search func(context.Context, models.FlightSearchRequest) ([]models.Flight, error)) {
+			defer wg.Done()
+			start := time.Now()
+			branchCtx, branchSpan := tracing.Tracer().Start(withBranch(ctx, name), "search."+name, trace.WithAttributes(
+				attribute.String("route", req.Origin+"-"+req.Destination),
+				attribute.String("search.branch", name),
+			))
+			flights, err := ro.searchEachPair(branchCtx, req, pairs, search)
+			endSpan(branchSpan, err, attribute.Int("search.flights", len(flights)))
+			result := BranchResult{Branch: name, Flights: flights, Err: err}
+			diag.recordBranch(result, time.Since(start))
+			results <- result
+		}(branch.name, branch.search)
+	}
+
+	// Wait for all searches to complete
 	go func() {
-		defer wg.Done()
-		flights, err := ro.searchDirectFlights(req)
-		if err != nil {
-			errors <- err
-			return
-		}
-		flightChan <- flights
+		wg.Wait()
+		close(results)
 	}()
 
-	// 2. Search 1-stop routes through major hubs
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		flights, err := ro.searchOneStopRoutes(ctx, req)
-		if err != nil {
-			errors <- err
-			return
+	// Collect all flights. Branch errors are reported to the observer but
+	// don't fail the entire request.
+	completed := 0
+	for result := range results {
+		completed++
+		result.Completed = completed
+		result.Total = len(branches)
+		if result.Err == nil {
+			allFlights = append(allFlights, result.Flights...)
+			slog.DebugContext(ctx, "search branch finished", "branch", result.Branch, "flights", len(result.Flights))
+		} else {
+			slog.WarnContext(ctx, "search branch failed", "branch", result.Branch, "error", result.Err)
 		}
-		flightChan <- flights
-	}()
+		if onBranch != nil {
+			onBranch(result)
+		}
+	}
 
-	// 3. Search 2-stop routes (more complex, limited hubs)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		flights, err := ro.searchTwoStopRoutes(ctx, req)
-		if err != nil {
-			errors <- err
-			return
+	// Keep the best tradeoffs of price, duration and stops
+	best := ro.selectBestFlights(allFlights, diag)
+	metrics.ObserveSearch(stats.Calls(), len(best))
+	span.SetAttributes(
+		attribute.String("status", "ok"),
+		attribute.Int("search.results", len(best)),
+		attribute.Int("search.upstream_calls", stats.Calls()),
+	)
+	return best, nil
+}
+
+// endSpan records the outcome of a unit of work on its span and ends it
+func endSpan(span trace.Span, err error, attrs ...attribute.KeyValue) {
+	span.SetAttributes(attrs...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("status", "error"))
+	} else {
+		span.SetAttributes(attribute.String("status", "ok"))
+	}
+	span.End()
+}
+
+// searchAirport is an airport a search covers. Nearby alternates record how
+// far they are from the airport that was asked for.
+type searchAirport struct {
+	code       string
+	nearby     bool
+	distanceKm float64
+}
+
+// airportPair is one origin and destination combination a search covers
+type airportPair struct {
+	origin, destination searchAirport
+}
+
+func (p airportPair) String() string {
+	return p.origin.code + "-" + p.destination.code
+}
+
+// airportPairs returns every origin and destination combination the request
+// covers, the requested airports first. When the two ends are close, an
+// airport asked for at one end never stands in for the other, so the trip is
+// never reversed.
+func (ro *RouteOptimizer) airportPairs(settings SearchSettings, req models.FlightSearchRequest) []airportPair {
+	origins := ro.searchAirports(settings, req.Origin, req.NearbyRadiusKm)
+	destinations := ro.searchAirports(settings, req.Destination, req.NearbyRadiusKm)
+	requested := func(airports []searchAirport, code string) bool {
+		for _, airport := range airports {
+			if airport.code == code && !airport.nearby {
+				return true
+			}
 		}
-		flightChan <- flights
-	}()
+		return false
+	}
 
-	// Wait for all searches to complete
-	go func() {
-		wg.Wait()
-		close(flightChan)
-		close(errors)
-	}()
+	var pairs []airportPair
+	for _, origin := range origins {
+		for _, destination := range destinations {
+			if origin.code == destination.code || requested(destinations, origin.code) || requested(origins, destination.code) {
+				continue
+			}
+			pairs = append(pairs, airportPair{origin, destination})
+		}
+	}
+	return pairs
+}
+
+// searchAirports returns the airports a location code stands for (the
+// members of a metropolitan area such as LON, or the airport itself),
+// followed by up to MaxNearbyAirports others within radiusKm of any of them,
+// nearest first
+func (ro *RouteOptimizer) searchAirports(settings SearchSettings, code string, radiusKm int) []searchAirport {
+	if ro.airportService == nil {
+		return []searchAirport{{code: code}}
+	}
+
+	members := ro.airportService.LocationAirports(code)
+	airports := make([]searchAirport, 0, len(members)+settings.MaxNearbyAirports)
+	seen := make(map[string]bool)
+	for _, member := range members {
+		airports = append(airports, searchAirport{code: member})
+		seen[member] = true
+	}
+	if radiusKm <= 0 {
+		return airports
+	}
+
+	// Measure each alternate from the closest member
+	closest := make(map[string]float64)
+	for _, member := range members {
+		for _, nearby := range ro.airportService.NearbyAirports(member, float64(radiusKm)) {
+			if seen[nearby.IATA] {
+				continue
+			}
+			if distance, ok := closest[nearby.IATA]; !ok || nearby.DistanceKm < distance {
+				closest[nearby.IATA] = nearby.DistanceKm
+			}
+		}
+	}
+	alternates := make([]searchAirport, 0, len(closest))
+	for alternate, distance := range closest {
+		alternates = append(alternates, searchAirport{code: alternate, nearby: true, distanceKm: math.Round(distance)})
+	}
+	sort.Slice(alternates, func(i, j int) bool {
+		if alternates[i].distanceKm != alternates[j].distanceKm {
+			return alternates[i].distanceKm < alternates[j].distanceKm
+		}
+		return alternates[i].code < alternates[j].code
+	})
+	if len(alternates) > settings.MaxNearbyAirports {
+		alternates = alternates[:settings.MaxNearbyAirports]
+	}
+	return append(airports, alternates...)
+}
+
+// searchEachPair runs a branch's search for every airport pair at once. It
+// only fails when every pair failed.
+func (ro *RouteOptimizer) searchEachPair(ctx context.Context, req models.FlightSearchRequest, pairs []airportPair, search func(context.Context, models.FlightSearchRequest) ([]models.Flight, error)) ([]models.Flight, error) {
+	if len(pairs) == 1 {
+		return searchPair(ctx, req, pairs[0], search)
+	}
+
+	var allFlights []models.Flight
+	var firstErr error
+	failed := 0
+	var wg sync.WaitGroup
+	var mu sync.Mutex
 
-	// Collect all flights
-	for flights := range flightChan {
-		mu.Lock()
-		allFlights = append(allFlights, flights...)
-		mu.Unlock()
+	for _, pair := range pairs {
+		wg.Add(1)
+		go func(pair airportPair) {
+			defer wg.Done()
+			flights, err := searchPair(ctx, req, pair, search)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", pair, err)
+				}
+				return
+			}
+			allFlights = append(allFlights, flights...)
+		}(pair)
 	}
+	wg.Wait()
 
-	// Log any errors but don't fail the entire request
-	for err := range errors {
-		// In production, you'd want proper logging here
-		_ = err // Ignore errors for now, but log them
+	if failed == len(pairs) {
+		return nil, firstErr
 	}
+	return allFlights, nil
+}
 
-	// Sort flights by price and return top 10
-	return ro.selectBestFlights(allFlights), nil
+// searchPair runs a branch's search between one pair of airports and labels
+// each flight with how far its airports are from the requested ones
+func searchPair(ctx context.Context, req models.FlightSearchRequest, pair airportPair, search func(context.Context, models.FlightSearchRequest) ([]models.Flight, error)) ([]models.Flight, error) {
+	pairReq := req
+	pairReq.Origin = pair.origin.code
+	pairReq.Destination = pair.destination.code
+
+	flights, err := search(ctx, pairReq)
+	for i := range flights {
+		flights[i].OriginDistanceKm = pair.origin.distanceKm
+		flights[i].DestinationDistanceKm = pair.destination.distanceKm
+	}
+	return flights, err
 }
 
 // searchDirectFlights searches for direct flights
-func (ro *RouteOptimizer) searchDirectFlights(req models.FlightSearchRequest) ([]models.Flight, error) {
-	amadeusResp, err := ro.amadeusService.SearchFlights(req)
+func (ro *RouteOptimizer) searchDirectFlights(ctx context.Context, req models.FlightSearchRequest) ([]models.Flight, error) {
+	amadeusResp, err := ro.amadeusService.SearchFlights(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
 	flights := ro.amadeusService.ConvertAmadeusFlights(amadeusResp, req)
 
+	// Keep every offer for the price history, not just the direct ones
+	ro.priceHistory.Record(ctx, flights)
+
 	// Filter for direct flights only
 	var directFlights []models.Flight
 	for _, flight := range flights {
@@ -141,15 +369,19 @@ func (ro *RouteOptimizer) searchDirectFlights(req models.FlightSearchRequest) ([
 // searchOneStopRoutes searches for one-stop routes through major hubs
 func (ro *RouteOptimizer) searchOneStopRoutes(ctx context.Context, req models.FlightSearchRequest) ([]models.Flight, error) {
 	var allFlights []models.Flight
-	hubs := ro.getRelevantHubs(req.Origin, req.Destination)
+	settings := ro.Settings()
+	hubs := ro.getRelevantHubs(settings, req.Origin, req.Destination)
+	diag := searchDiagnosticsFromContext(ctx)
+	diag.recordHubs(hubs, nil)
 
 	// Limit concurrent requests to avoid overwhelming the API
-	semaphore := make(chan struct{}, 5)
+	semaphore := make(chan struct{}, settings.OneStopConcurrency)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
 	for _, hub := range hubs {
 		if hub == req.Origin || hub == req.Destination {
+			diag.recordCombination(BranchOneStop, models.CombinationExcluded, hub)
 			continue
 		}
 
@@ -161,11 +393,13 @@ func (ro *RouteOptimizer) searchOneStopRoutes(ctx context.Context, req models.Fl
 			case semaphore <- struct{}{}:
 				defer func() { <-semaphore }()
 			case <-ctx.Done():
+				diag.recordCombination(BranchOneStop, models.CombinationCancelled, hubCode)
 				return
 			}
+			diag.recordCombination(BranchOneStop, models.CombinationRan, hubCode)
 
 			// Search origin -> hub -> destination
-			flights := ro.searchViaHub(req, hubCode)
+			flights := ro.searchViaHub(ctx, req, hubCode)
 
 			mu.Lock()
 			allFlights = append(allFlights, flights...)
@@ -182,22 +416,28 @@ func (ro *RouteOptimizer) searchTwoStopRoutes(ctx context.Context, req models.Fl
 	var allFlights []models.Flight
 
 	// For 2-stop routes, we'll be more selective with hubs to avoid too many API calls
-	majorHubs := ro.getMajorHubs(req.Origin, req.Destination)
+	settings := ro.Settings()
+	majorHubs := ro.getMajorHubs(settings, req.Origin, req.Destination)
+	diag := searchDiagnosticsFromContext(ctx)
 
-	// Limit to top 10 hub combinations for 2-stop routes
-	if len(majorHubs) > 10 {
-		majorHubs = majorHubs[:10]
+	// Limit the hubs combined for 2-stop routes
+	if len(majorHubs) > settings.MaxTwoStopHubs {
+		majorHubs = majorHubs[:settings.MaxTwoStopHubs]
 	}
+	diag.recordHubs(nil, majorHubs)
 
-	semaphore := make(chan struct{}, 3) // Even more limited for 2-stop
+	semaphore := make(chan struct{}, settings.TwoStopConcurrency) // Usually more limited for 2-stop
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
 	// Try combinations of 2 hubs
 	for i, hub1 := range majorHubs {
 		for j, hub2 := range majorHubs {
-			if i >= j || hub1 == hub2 || hub1 == req.Origin || hub1 == req.Destination ||
-				hub2 == req.Origin || hub2 == req.Destination {
+			if i >= j || hub1 == hub2 {
+				continue
+			}
+			if hub1 == req.Origin || hub1 == req.Destination || hub2 == req.Origin || hub2 == req.Destination {
+				diag.recordCombination(BranchTwoStop, models.CombinationExcluded, hub1, hub2)
 				continue
 			}
 
@@ -209,11 +449,13 @@ func (ro *RouteOptimizer) searchTwoStopRoutes(ctx context.Context, req models.Fl
 				case semaphore <- struct{}{}:
 					defer func() { <-semaphore }()
 				case <-ctx.Done():
+					diag.recordCombination(BranchTwoStop, models.CombinationCancelled, h1, h2)
 					return
 				}
+				diag.recordCombination(BranchTwoStop, models.CombinationRan, h1, h2)
 
 				// Search origin -> hub1 -> hub2 -> destination
-				flights := ro.searchViaTwoHubs(req, h1, h2)
+				flights := ro.searchViaTwoHubs(ctx, req, h1, h2)
 
 				mu.Lock()
 				allFlights = append(allFlights, flights...)
@@ -227,7 +469,13 @@ func (ro *RouteOptimizer) searchTwoStopRoutes(ctx context.Context, req models.Fl
 }
 
 // searchViaHub searches for flights via a single hub
-func (ro *RouteOptimizer) searchViaHub(req models.FlightSearchRequest, hub string) []models.Flight {
+func (ro *RouteOptimizer) searchViaHub(ctx context.Context, req models.FlightSearchRequest, hub string) []models.Flight {
+	_, span := tracing.Tracer().Start(ctx, "search.hub_leg", trace.WithAttributes(
+		attribute.String("route", req.Origin+"-"+hub+"-"+req.Destination),
+		attribute.String("hub", hub),
+	))
+	defer endSpan(span, nil)
+
 	// This is a simplified implementation
 	// In reality, you'd need to search for two separate flights and combine them
 
@@ -235,104 +483,109 @@ func (ro *RouteOptimizer) searchViaHub(req models.FlightSearchRequest, hub strin
 	// In production, you'd make separate API calls for each leg
 
 	mockFlight := models.Flight{
-		ID:          "multi-" + req.Origin + "-" + hub + "-" + req.Destination,
-		Origin:      req.Origin,
-		Destination: req.Destination,
-		Date:        req.Date,
-		Price:       350.0 + float64(len(hub)*10), // Mock pricing logic
-		Currency:    "USD",
-		Airline:     "Multi-Airline",
-		Duration:    "8h 30m", // Mock duration
-		Stops:       1,
-		Route:       []string{req.Origin, hub, req.Destination},
+		ID:              "multi-" + req.Origin + "-" + hub + "-" + req.Destination,
+		Origin:          req.Origin,
+		Destination:     req.Destination,
+		Date:            req.Date,
+		Price:           350.0 + float64(len(hub)*10), // Mock pricing logic
+		Currency:        ro.amadeusService.Currency,
+		Airline:         "Multi-Airline",
+		Duration:        "8h 30m", // Mock duration
+		Stops:           1,
+		DurationMinutes: 510,
+		Route:           []string{req.Origin, hub, req.Destination},
 	}
 
 	return []models.Flight{mockFlight}
 }
 
 // searchViaTwoHubs searches for flights via two hubs
-func (ro *RouteOptimizer) searchViaTwoHubs(req models.FlightSearchRequest, hub1, hub2 string) []models.Flight {
+func (ro *RouteOptimizer) searchViaTwoHubs(ctx context.Context, req models.FlightSearchRequest, hub1, hub2 string) []models.Flight {
+	_, span := tracing.Tracer().Start(ctx, "search.hub_leg", trace.WithAttributes(
+		attribute.String("route", req.Origin+"-"+hub1+"-"+hub2+"-"+req.Destination),
+		attribute.String("hub", hub1+","+hub2),
+	))
+	defer endSpan(span, nil)
+
 	// Mock implementation for 2-stop routes
 	mockFlight := models.Flight{
-		ID:          "multi2-" + req.Origin + "-" + hub1 + "-" + hub2 + "-" + req.Destination,
-		Origin:      req.Origin,
-		Destination: req.Destination,
-		Date:        req.Date,
-		Price:       250.0 + float64(len(hub1+hub2)*5), // Mock pricing - often cheaper due to complexity
-		Currency:    "USD",
-		Airline:     "Multi-Airline Express",
-		Duration:    "12h 45m", // Mock duration
-		Stops:       2,
-		Route:       []string{req.Origin, hub1, hub2, req.Destination},
+		ID:              "multi2-" + req.Origin + "-" + hub1 + "-" + hub2 + "-" + req.Destination,
+		Origin:          req.Origin,
+		Destination:     req.Destination,
+		Date:            req.Date,
+		Price:           250.0 + float64(len(hub1+hub2)*5), // Mock pricing - often cheaper due to complexity
+		Currency:        ro.amadeusService.Currency,
+		Airline:         "Multi-Airline Express",
+		Duration:        "12h 45m", // Mock duration
+		Stops:           2,
+		DurationMinutes: 765,
+		Route:           []string{req.Origin, hub1, hub2, req.Destination},
 	}
 
 	return []models.Flight{mockFlight}
 }
 
 // getRelevantHubs returns relevant hub airports based on route
-func (ro *RouteOptimizer) getRelevantHubs(origin, destination string) []string {
+func (ro *RouteOptimizer) getRelevantHubs(settings SearchSettings, origin, destination string) []string {
 	var hubs []string
 
 	// Get hubs from all regions but prioritize based on route
-	for _, regionHubs := range ro.hubAirports {
+	for _, regionHubs := range settings.HubAirports {
 		hubs = append(hubs, regionHubs...)
 	}
 
 	// In production, you'd use geographic/route logic to filter relevant hubs
 	// For now, return all major hubs but limit the number
-	if len(hubs) > 20 {
-		hubs = hubs[:20]
+	if len(hubs) > settings.MaxRelevantHubs {
+		hubs = hubs[:settings.MaxRelevantHubs]
 	}
 
 	return hubs
 }
 
 // getMajorHubs returns the most important hub airports
-func (ro *RouteOptimizer) getMajorHubs(origin, destination string) []string {
-	majorHubs := []string{
-		"DXB", "DOH", "IST", "FRA", "LHR", "CDG", "AMS",
-		"SIN", "HKG", "ICN", "NRT", "PVG",
-		"JFK", "LAX", "ORD", "DFW", "ATL",
-	}
-
-	return majorHubs
+func (ro *RouteOptimizer) getMajorHubs(settings SearchSettings, origin, destination string) []string {
+	return settings.MajorHubs
 }
 
-// selectBestFlights sorts flights by price and returns the top 10
-func (ro *RouteOptimizer) selectBestFlights(flights []models.Flight) []models.Flight {
+// selectBestFlights keeps the flights that no other flight beats on price,
+// total duration and stops at once, cheapest first, and tags the cheapest,
+// the fastest and the best value among them. At most TopResults are kept, the
+// tagged ones always. Candidates that don't make it are reported to diag,
+// which may be nil.
+func (ro *RouteOptimizer) selectBestFlights(flights []models.Flight, diag *SearchDiagnostics) []models.Flight {
+	diag.recordCandidates(len(flights))
 	if len(flights) == 0 {
 		return flights
 	}
 
-	// Sort flights by price (ascending)
-	sort.Slice(flights, func(i, j int) bool {
-		return flights[i].Price < flights[j].Price
+	sort.SliceStable(flights, func(i, j int) bool {
+		return cheaperFlight(flights[i], flights[j])
 	})
 
 	// Remove duplicates based on route and price
-	uniqueFlights := ro.removeDuplicateFlights(flights)
-
-	// Return top 10
-	if len(uniqueFlights) > 10 {
-		return uniqueFlights[:10]
-	}
+	uniqueFlights := ro.removeDuplicateFlights(flights, diag)
 
-	return uniqueFlights
+	frontier := paretoFrontier(uniqueFlights, diag)
+	tagFlights(frontier)
+	return trimFrontier(frontier, ro.Settings().TopResults, diag)
 }
 
 // removeDuplicateFlights removes duplicate flights based on route similarity
-func (ro *RouteOptimizer) removeDuplicateFlights(flights []models.Flight) []models.Flight {
-	seen := make(map[string]bool)
+func (ro *RouteOptimizer) removeDuplicateFlights(flights []models.Flight, diag *SearchDiagnostics) []models.Flight {
+	seen := make(map[string]string) // key -> ID of the flight kept for it
 	var unique []models.Flight
 
 	for _, flight := range flights {
 		// Create a key based on route and approximate price
 		key := fmt.Sprintf("%v-%.0f", flight.Route, flight.Price/10*10)
 
-		if !seen[key] {
-			seen[key] = true
-			unique = append(unique, flight)
+		if keptID, ok := seen[key]; ok {
+			diag.recordDropped(flight, "duplicate of "+keptID)
+			continue
 		}
+		seen[key] = flight.ID
+		unique = append(unique, flight)
 	}
 
 	return unique
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"cheapest-flight-backend/models"
+)
+
+// SearchDiagnostics collects explain-mode details for a single search. A nil
+// collector ignores everything, so searches without explain pay nothing.
+type SearchDiagnostics struct {
+	mu       sync.Mutex
+	report   models.SearchDiagnostics
+	branches map[string]*models.BranchDiagnostics
+}
+
+type searchDiagnosticsKey struct{}
+
+type branchKey struct{}
+
+// WithSearchDiagnostics attaches a fresh diagnostics collector to the context
+func WithSearchDiagnostics(ctx context.Context) (context.Context, *SearchDiagnostics) {
+	diag := &SearchDiagnostics{branches: make(map[string]*models.BranchDiagnostics)}
+	return context.WithValue(ctx, searchDiagnosticsKey{}, diag), diag
+}
+
+// searchDiagnosticsFromContext returns the collector attached to ctx, if any
+func searchDiagnosticsFromContext(ctx context.Context) *SearchDiagnostics {
+	diag, _ := ctx.Value(searchDiagnosticsKey{}).(*SearchDiagnostics)
+	return diag
+}
+
+// withBranch tags ctx with the search branch it belongs to, so upstream calls
+// can be attributed to it
+func withBranch(ctx context.Context, branch string) context.Context {
+	return context.WithValue(ctx, branchKey{}, branch)
+}
+
+// Report returns a snapshot of everything collected so far, with branches in
+// the order they were started
+func (d *SearchDiagnostics) Report() *models.SearchDiagnostics {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	report := d.report
+	report.Branches = make([]models.BranchDiagnostics, 0, len(d.branches))
+	for _, name := range []string{BranchDirect, BranchOneStop, BranchTwoStop} {
+		if branch, ok := d.branches[name]; ok {
+			report.Branches = append(report.Branches, *branch)
+		}
+	}
+	report.Combinations = append([]models.HubCombination{}, d.report.Combinations...)
+	sort.SliceStable(report.Combinations, func(i, j int) bool {
+		return report.Combinations[i].Branch < report.Combinations[j].Branch
+	})
+	report.Dropped = append([]models.DroppedFlight{}, d.report.Dropped...)
+	return &report
+}
+
+// branch returns the entry for name, creating it. Callers must hold the lock.
+func (d *SearchDiagnostics) branch(name string) *models.BranchDiagnostics {
+	branch, ok := d.branches[name]
+	if !ok {
+		branch = &models.BranchDiagnostics{Branch: name}
+		d.branches[name] = branch
+	}
+	return branch
+}
+
+func (d *SearchDiagnostics) recordAirportPairs(pairs []airportPair) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.report.AirportPairs = make([]string, len(pairs))
+	for i, pair := range pairs {
+		d.report.AirportPairs[i] = pair.String()
+	}
+}
+
+func (d *SearchDiagnostics) recordHubs(relevant, major []string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if relevant != nil {
+		d.report.RelevantHubs = append([]string(nil), relevant...)
+	}
+	if major != nil {
+		d.report.MajorHubs = append([]string(nil), major...)
+	}
+}
+
+func (d *SearchDiagnostics) recordCombination(branch, status string, hubs ...string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.report.Combinations = append(d.report.Combinations, models.HubCombination{Branch: branch, Hubs: hubs, Status: status})
+}
+
+// recordUpstreamCall attributes an Amadeus call to the branch tagged on ctx
+func (d *SearchDiagnostics) recordUpstreamCall(ctx context.Context, elapsed time.Duration) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.report.UpstreamCalls++
+	if name, ok := ctx.Value(branchKey{}).(string); ok {
+		branch := d.branch(name)
+		branch.UpstreamCalls++
+		branch.UpstreamLatency += elapsed.Milliseconds()
+	}
+}
+
+func (d *SearchDiagnostics) recordTokenCache(hit bool) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if hit {
+		d.report.TokenCacheHits++
+	} else {
+		d.report.TokenCacheMisses++
+	}
+}
+
+func (d *SearchDiagnostics) recordBranch(result BranchResult, elapsed time.Duration) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	branch := d.branch(result.Branch)
+	branch.DurationMs = elapsed.Milliseconds()
+	branch.Flights = len(result.Flights)
+	if result.Err != nil {
+		branch.Error = result.Err.Error()
+	}
+}
+
+func (d *SearchDiagnostics) recordCandidates(n int) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.report.Candidates = n
+}
+
+func (d *SearchDiagnostics) recordDropped(flight models.Flight, reason string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.report.Dropped = append(d.report.Dropped, models.DroppedFlight{
+		ID:     flight.ID,
+		Route:  flight.Route,
+		Price:  flight.Price,
+		Reason: reason,
+	})
+}
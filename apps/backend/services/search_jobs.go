@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"cheapest-flight-backend/logging"
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/store"
+	"cheapest-flight-backend/utils"
+)
+
+// ErrJobQueueFull is returned when no more search jobs can be queued
+var ErrJobQueueFull = errors.New("search job queue is full")
+
+// SearchJobManager runs flight searches in the background on a bounded pool
+// of workers. Active jobs are tracked in memory and every state change is
+// written to the job store, so finished results survive a restart until they
+// expire.
+type SearchJobManager struct {
+	optimizer *RouteOptimizer
+	store     store.JobStore
+	workers   int
+	timeout   time.Duration
+	ttl       time.Duration
+	queue     chan string
+
+	mu         sync.RWMutex
+	jobs       map[string]*models.SearchJob
+	onComplete []func(models.SearchJob)
+}
+
+func NewSearchJobManager(optimizer *RouteOptimizer, jobStore store.JobStore, workers, queueSize int, timeout, ttl time.Duration) *SearchJobManager {
+	if workers < 1 {
+		workers = 1
+	}
+	return &SearchJobManager{
+		optimizer: optimizer,
+		store:     jobStore,
+		workers:   workers,
+		timeout:   timeout,
+		ttl:       ttl,
+		queue:     make(chan string, queueSize),
+		jobs:      make(map[string]*models.SearchJob),
+	}
+}
+
+// Start launches the worker pool and the expiry janitor. They stop when ctx
+// is cancelled.
+func (m *SearchJobManager) Start(ctx context.Context) {
+	for i := 0; i < m.workers; i++ {
+		go m.worker(ctx)
+	}
+	go m.janitor(ctx)
+}
+
+// OnComplete registers a callback invoked when a job completes or fails
+func (m *SearchJobManager) OnComplete(fn func(models.SearchJob)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onComplete = append(m.onComplete, fn)
+}
+
+// Submit queues a search for the given user, empty for anonymous searches,
+// and returns the new job right away. The request ID in ctx follows the job
+// onto its worker.
+func (m *SearchJobManager) Submit(ctx context.Context, userID string, req models.FlightSearchRequest) (models.SearchJob, error) {
+	now := time.Now().UTC()
+	job := &models.SearchJob{
+		ID:                utils.NewID(),
+		Status:            models.JobQueued,
+		CompletedBranches: []string{},
+		Query:             req,
+		UserID:            userID,
+		Flights:           []models.Flight{},
+		RequestID:         logging.RequestID(ctx),
+		CreatedAt:         now,
+		ExpiresAt:         now.Add(m.ttl),
+	}
+
+	m.mu.Lock()
+	m.jobs[job.ID] = job
+	m.mu.Unlock()
+
+	snapshot := m.snapshot(job)
+	if err := m.store.SaveJob(ctx, snapshot); err != nil {
+		m.mu.Lock()
+		delete(m.jobs, job.ID)
+		m.mu.Unlock()
+		return models.SearchJob{}, err
+	}
+
+	select {
+	case m.queue <- job.ID:
+	default:
+		m.mu.Lock()
+		delete(m.jobs, job.ID)
+		m.mu.Unlock()
+		snapshot.Status = models.JobFailed
+		snapshot.Error = ErrJobQueueFull.Error()
+		m.persist(ctx, snapshot)
+		return models.SearchJob{}, ErrJobQueueFull
+	}
+
+	return snapshot, nil
+}
+
+// Get returns a copy of the job with the given ID
+func (m *SearchJobManager) Get(ctx context.Context, id string) (models.SearchJob, bool) {
+	m.mu.RLock()
+	job, exists := m.jobs[id]
+	if exists {
+		snapshot := m.snapshotLocked(job)
+		m.mu.RUnlock()
+		return snapshot, true
+	}
+	m.mu.RUnlock()
+
+	// Jobs from before a restart are only in the store
+	stored, err := m.store.GetJob(ctx, id)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			slog.ErrorContext(ctx, "failed to load search job", "job_id", id, "error", err)
+		}
+		return models.SearchJob{}, false
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return models.SearchJob{}, false
+	}
+	if stored.Status == models.JobQueued || stored.Status == models.JobRunning {
+		stored.Status = models.JobFailed
+		stored.Error = "search was interrupted by a server restart"
+	}
+	return stored, true
+}
+
+func (m *SearchJobManager) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-m.queue:
+			m.run(ctx, id)
+		}
+	}
+}
+
+// run executes a single job, publishing partial results as branches finish
+func (m *SearchJobManager) run(parent context.Context, id string) {
+	m.mu.Lock()
+	job, exists := m.jobs[id]
+	if !exists {
+		m.mu.Unlock()
+		return
+	}
+	started := time.Now().UTC()
+	job.Status = models.JobRunning
+	job.StartedAt = &started
+	req := job.Query
+	snapshot := m.snapshotLocked(job)
+	m.mu.Unlock()
+
+	if job.RequestID != "" {
+		parent = logging.WithRequestID(parent, job.RequestID)
+	}
+	m.persist(parent, snapshot)
+
+	ctx, cancel := context.WithTimeout(parent, m.timeout)
+	defer cancel()
+	ctx, stats := WithSearchStats(ctx)
+
+	var collected []models.Flight
+	flights, err := m.optimizer.OptimizeRoutesWithProgress(ctx, req, func(result BranchResult) {
+		if result.Err == nil {
+			collected = append(collected, result.Flights...)
+		}
+		partial := m.optimizer.selectBestFlights(append([]models.Flight(nil), collected...), nil)
+
+		m.mu.Lock()
+		job.Progress = float64(result.Completed) / float64(result.Total)
+		job.CompletedBranches = append(job.CompletedBranches, result.Branch)
+		job.Flights = partial
+		job.Total = len(partial)
+		job.UpstreamCalls = stats.Calls()
+		snapshot := m.snapshotLocked(job)
+		m.mu.Unlock()
+
+		m.persist(parent, snapshot)
+	})
+
+	m.mu.Lock()
+	completed := time.Now().UTC()
+	job.CompletedAt = &completed
+	job.ExpiresAt = completed.Add(m.ttl)
+	job.UpstreamCalls = stats.Calls()
+	if err != nil {
+		job.Status = models.JobFailed
+		job.Error = err.Error()
+		slog.WarnContext(ctx, "search job failed", "job_id", id, "error", err)
+	} else {
+		if flights == nil {
+			flights = []models.Flight{}
+		}
+		job.Status = models.JobCompleted
+		job.Progress = 1
+		job.Flights = flights
+		job.Total = len(flights)
+		slog.InfoContext(ctx, "search job completed", "job_id", id, "flights", len(flights), "upstream_calls", stats.Calls())
+	}
+	final := m.snapshotLocked(job)
+	callbacks := m.onComplete
+	m.mu.Unlock()
+
+	m.persist(parent, final)
+
+	for _, fn := range callbacks {
+		fn(final)
+	}
+}
+
+// janitor removes jobs once they pass their expiry time
+func (m *SearchJobManager) janitor(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			for id, job := range m.jobs {
+				// Finished jobs stay readable from the store until they expire
+				if job.Status != models.JobQueued && job.Status != models.JobRunning {
+					delete(m.jobs, id)
+				}
+			}
+			m.mu.Unlock()
+
+			if _, err := m.store.DeleteExpiredJobs(ctx, now); err != nil {
+				slog.ErrorContext(ctx, "failed to delete expired search jobs", "error", err)
+			}
+		}
+	}
+}
+
+// persist writes a job snapshot to the store. Failures are logged; the
+// in-memory copy stays authoritative while the job is active.
+func (m *SearchJobManager) persist(ctx context.Context, job models.SearchJob) {
+	// Detached from cancellation so the final state is saved even on shutdown
+	if err := m.store.SaveJob(context.WithoutCancel(ctx), job); err != nil {
+		slog.ErrorContext(ctx, "failed to save search job", "job_id", job.ID, "error", err)
+	}
+}
+
+func (m *SearchJobManager) snapshot(job *models.SearchJob) models.SearchJob {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.snapshotLocked(job)
+}
+
+// snapshotLocked copies a job so callers can't race with the worker. Callers
+// must hold mu.
+func (m *SearchJobManager) snapshotLocked(job *models.SearchJob) models.SearchJob {
+	copied := *job
+	copied.Flights = append([]models.Flight{}, job.Flights...)
+	copied.CompletedBranches = append([]string{}, job.CompletedBranches...)
+	return copied
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"cheapest-flight-backend/logging"
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/store"
+	"cheapest-flight-backend/utils"
+)
+
+// WatchScheduler periodically re-runs the search behind every price watch and
+// records when the best price crosses the watch threshold
+type WatchScheduler struct {
+	store         store.WatchStore
+	optimizer     *RouteOptimizer
+	interval      time.Duration
+	budgetReserve int // calls left for interactive searches before watches pause
+	searchTimeout time.Duration
+
+	mu         sync.Mutex
+	onCrossing []func(models.PriceWatch, models.PriceWatchCrossing)
+}
+
+func NewWatchScheduler(watchStore store.WatchStore, optimizer *RouteOptimizer, interval time.Duration, budgetReserve int) *WatchScheduler {
+	return &WatchScheduler{
+		store:         watchStore,
+		optimizer:     optimizer,
+		interval:      interval,
+		budgetReserve: budgetReserve,
+		searchTimeout: 60 * time.Second,
+	}
+}
+
+// OnCrossing registers a callback invoked when a watch crosses its threshold
+func (s *WatchScheduler) OnCrossing(fn func(models.PriceWatch, models.PriceWatchCrossing)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onCrossing = append(s.onCrossing, fn)
+}
+
+// Start checks all watches every interval until ctx is cancelled
+func (s *WatchScheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.CheckAll(ctx)
+			}
+		}
+	}()
+}
+
+// CheckAll runs every active watch once, one at a time so watches never
+// compete with interactive searches for concurrency
+func (s *WatchScheduler) CheckAll(ctx context.Context) {
+	watches, err := s.store.ListWatches(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "watch scheduler: failed to list watches", "error", err)
+		return
+	}
+
+	checked := 0
+	var orphaned []string
+	defer func() {
+		if len(orphaned) > 0 {
+			slog.WarnContext(ctx, "watch scheduler: skipped watches without an owner", "count", len(orphaned), "watch_ids", orphaned)
+		}
+	}()
+	for _, watch := range watches {
+		if ctx.Err() != nil {
+			return
+		}
+		if watchExpired(watch) {
+			continue
+		}
+		// Watches created before watches had owners notify nobody, so
+		// checking them would only spend quota
+		if watch.UserID == "" {
+			orphaned = append(orphaned, watch.ID)
+			continue
+		}
+		if !s.budgetAvailable() {
+			slog.WarnContext(ctx, "watch scheduler: call budget reserve reached", "deferred", len(watches)-checked)
+			return
+		}
+
+		// Each scheduled check gets its own ID to trace it like a request
+		checkCtx := logging.WithRequestID(ctx, utils.NewID())
+		if _, err := s.CheckWatch(checkCtx, watch.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			slog.WarnContext(checkCtx, "watch scheduler: watch check failed", "watch_id", watch.ID, "error", err)
+		}
+		checked++
+	}
+
+	if checked > 0 {
+		slog.InfoContext(ctx, "watch scheduler: checked watches", "checked", checked)
+	}
+}
+
+// budgetAvailable reports whether enough of today's quota is left to spend
+// some on watches
+func (s *WatchScheduler) budgetAvailable() bool {
+	budget := s.optimizer.amadeusService.Budget()
+	if budget == nil {
+		return true
+	}
+	remaining := budget.Remaining()
+	return remaining < 0 || remaining > s.budgetReserve
+}
+
+// CheckWatch re-runs the search for one watch and stores the outcome
+func (s *WatchScheduler) CheckWatch(ctx context.Context, id string) (models.PriceWatch, error) {
+	watch, err := s.store.GetWatch(ctx, id)
+	if err != nil {
+		return models.PriceWatch{}, err
+	}
+
+	searchCtx, cancel := context.WithTimeout(ctx, s.searchTimeout)
+	defer cancel()
+	flights, searchErr := s.optimizer.OptimizeRoutes(searchCtx, watch.SearchRequest())
+
+	// Re-read so edits made while the search ran aren't overwritten
+	watch, err = s.store.GetWatch(ctx, id)
+	if err != nil {
+		return models.PriceWatch{}, err
+	}
+
+	now := time.Now().UTC()
+	watch.LastCheckedAt = &now
+
+	var crossing *models.PriceWatchCrossing
+	switch {
+	case searchErr != nil:
+		watch.LastError = searchErr.Error()
+	case len(flights) == 0:
+		watch.LastError = "no flights found"
+	default:
+		watch.LastError = ""
+		best := cheapestFlight(flights)
+		price := best.Price
+		watch.LastBestPrice = &price
+		watch.LastCurrency = best.Currency
+
+		below := best.Price <= watch.MaxPrice
+		if below != watch.BelowThreshold {
+			direction := models.CrossedAbove
+			if below {
+				direction = models.CrossedBelow
+			}
+			crossing = &models.PriceWatchCrossing{
+				At:        now,
+				Direction: direction,
+				Price:     best.Price,
+				Currency:  best.Currency,
+				Threshold: watch.MaxPrice,
+				FlightID:  best.ID,
+				Route:     best.Route,
+			}
+			watch.AddCrossing(*crossing)
+			watch.BelowThreshold = below
+		}
+	}
+
+	if err := s.store.SaveWatch(ctx, watch); err != nil {
+		return models.PriceWatch{}, err
+	}
+
+	if crossing != nil {
+		slog.InfoContext(ctx, "watch crossed threshold",
+			"watch_id", watch.ID,
+			"origin", watch.Origin,
+			"destination", watch.Destination,
+			"date", watch.Date,
+			"direction", crossing.Direction,
+			"threshold", crossing.Threshold,
+			"price", crossing.Price,
+			"currency", crossing.Currency,
+		)
+
+		s.mu.Lock()
+		callbacks := s.onCrossing
+		s.mu.Unlock()
+		for _, fn := range callbacks {
+			fn(watch, *crossing)
+		}
+	}
+
+	return watch, searchErr
+}
+
+// watchExpired reports whether the watched travel date has already passed
+func watchExpired(watch models.PriceWatch) bool {
+	date, err := time.Parse("2006-01-02", watch.Date)
+	if err != nil {
+		return true
+	}
+	return date.Before(time.Now().UTC().Truncate(24 * time.Hour))
+}
+
+func cheapestFlight(flights []models.Flight) models.Flight {
+	best := flights[0]
+	for _, flight := range flights[1:] {
+		if flight.Price < best.Price {
+			best = flight
+		}
+	}
+	return best
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/store"
+)
+
+func TestCheckAllSkipsWatchesWithoutOwners(t *testing.T) {
+	ctx := context.Background()
+
+	// Count upstream calls; failing them is enough to see whether a check ran
+	var calls atomic.Int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+
+	airports, err := NewAirportService("")
+	if err != nil {
+		t.Fatalf("NewAirportService: %v", err)
+	}
+	amadeus := NewAmadeusService(upstream.URL, []AmadeusCredentials{{ClientID: "k", ClientSecret: "s"}}, "failover", nil)
+	optimizer := NewRouteOptimizer(amadeus, airports, nil, SearchSettings{TopResults: 5})
+
+	db := store.NewMemoryStore()
+	now := time.Now().UTC()
+	date := now.AddDate(0, 1, 0).Format("2006-01-02")
+	watch := models.PriceWatch{
+		ID:                "watch-1",
+		PriceWatchRequest: models.PriceWatchRequest{Origin: "JFK", Destination: "LAX", Date: date, Passengers: 1, MaxPrice: 300},
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if err := db.SaveWatch(ctx, watch); err != nil {
+		t.Fatalf("SaveWatch: %v", err)
+	}
+
+	scheduler := NewWatchScheduler(db, optimizer, time.Hour, 0)
+	scheduler.CheckAll(ctx)
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("unowned watch made %d upstream calls, want 0", n)
+	}
+
+	watch.UserID = "user-1"
+	if err := db.SaveWatch(ctx, watch); err != nil {
+		t.Fatalf("SaveWatch: %v", err)
+	}
+	scheduler.CheckAll(ctx)
+	if calls.Load() == 0 {
+		t.Error("owned watch was not checked")
+	}
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"cheapest-flight-backend/logging"
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/store"
+	"cheapest-flight-backend/utils"
+)
+
+// Headers sent with every webhook delivery. The signature is the hex HMAC-SHA256
+// of "<timestamp>.<body>" keyed with the webhook secret, so receivers can check
+// both the payload and its freshness.
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookIDHeader        = "X-Webhook-ID"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// ErrPrivateWebhookAddress is returned for webhook URLs that point at
+// loopback, link-local or private network addresses, unless the dispatcher
+// allows private destinations
+var ErrPrivateWebhookAddress = errors.New("webhook URL must not point at a private, loopback or link-local address")
+
+type webhookJob struct {
+	webhook   models.Webhook
+	event     models.WebhookEvent
+	requestID string // of the request or job that raised the event
+}
+
+// WebhookDispatcher delivers signed event payloads to registered webhooks,
+// retrying failed deliveries with exponential backoff and logging every
+// attempt
+type WebhookDispatcher struct {
+	store          store.WebhookStore
+	client         *http.Client
+	maxAttempts    int
+	initialBackoff time.Duration
+	queue          chan webhookJob
+	workers        int
+	allowPrivate   bool
+}
+
+// NewWebhookDispatcher creates a dispatcher. allowPrivate lets webhooks reach
+// private, loopback and link-local addresses, for testing against a local
+// receiver.
+func NewWebhookDispatcher(webhookStore store.WebhookStore, maxAttempts int, initialBackoff, timeout time.Duration, allowPrivate bool) *WebhookDispatcher {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	// Every connection, including redirects, is checked at dial time so a
+	// hostname that later resolves to an internal address is still refused.
+	// Proxies are skipped since they would hide the real destination.
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if !allowPrivate {
+		dialer.Control = checkWebhookDial
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &WebhookDispatcher{
+		store: webhookStore,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		},
+		maxAttempts:    maxAttempts,
+		initialBackoff: initialBackoff,
+		queue:          make(chan webhookJob, 256),
+		workers:        4,
+		allowPrivate:   allowPrivate,
+	}
+}
+
+// Start launches the delivery workers. They stop when ctx is cancelled.
+func (d *WebhookDispatcher) Start(ctx context.Context) {
+	for i := 0; i < d.workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job := <-d.queue:
+					jobCtx := ctx
+					if job.requestID != "" {
+						jobCtx = logging.WithRequestID(ctx, job.requestID)
+					}
+					d.Deliver(jobCtx, job.webhook, job.event)
+				}
+			}
+		}()
+	}
+}
+
+// NewEvent wraps data in an event envelope with a fresh ID
+func NewEvent(eventType string, data interface{}) models.WebhookEvent {
+	return models.WebhookEvent{
+		ID:        utils.NewID(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// Publish queues an event for the owner's webhooks subscribed to its type.
+// Events without an owner, such as anonymous searches, go nowhere.
+func (d *WebhookDispatcher) Publish(ctx context.Context, ownerID, eventType string, data interface{}) {
+	if ownerID == "" {
+		return
+	}
+
+	webhooks, err := d.store.ListUserWebhooks(ctx, ownerID)
+	if err != nil {
+		slog.ErrorContext(ctx, "webhooks: failed to list webhooks", "event_type", eventType, "user_id", ownerID, "error", err)
+		return
+	}
+
+	event := NewEvent(eventType, data)
+	for _, webhook := range webhooks {
+		if !webhook.Subscribed(eventType) {
+			continue
+		}
+		select {
+		case d.queue <- webhookJob{webhook: webhook, event: event, requestID: logging.RequestID(ctx)}:
+		default:
+			slog.WarnContext(ctx, "webhooks: delivery queue full, dropping event",
+				"event_type", eventType, "event_id", event.ID, "webhook_id", webhook.ID)
+		}
+	}
+}
+
+// Deliver sends an event to one webhook, retrying until it succeeds, the
+// receiver rejects it permanently or the attempts run out. The last
+// attempt's log entry is returned.
+func (d *WebhookDispatcher) Deliver(ctx context.Context, webhook models.Webhook, event models.WebhookEvent) models.WebhookDelivery {
+	body, err := json.Marshal(event)
+	if err != nil {
+		slog.ErrorContext(ctx, "webhooks: failed to encode event", "event_id", event.ID, "error", err)
+		return models.WebhookDelivery{}
+	}
+
+	backoff := d.initialBackoff
+	var delivery models.WebhookDelivery
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		var retry bool
+		delivery, retry = d.attempt(ctx, webhook, event, body, attempt)
+		if err := d.store.AddDelivery(ctx, delivery); err != nil {
+			slog.ErrorContext(ctx, "webhooks: failed to record delivery", "delivery_id", delivery.ID, "error", err)
+		}
+		if delivery.Success || !retry || attempt == d.maxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return delivery
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	if !delivery.Success {
+		slog.WarnContext(ctx, "webhooks: giving up on delivery",
+			"event_type", event.Type,
+			"event_id", event.ID,
+			"webhook_id", webhook.ID,
+			"attempts", delivery.Attempt,
+			"error", delivery.Error,
+		)
+	}
+	return delivery
+}
+
+// Ping makes a single delivery of a test event without retries
+func (d *WebhookDispatcher) Ping(ctx context.Context, webhook models.Webhook) models.WebhookDelivery {
+	event := NewEvent(models.EventPing, map[string]interface{}{
+		"webhookId": webhook.ID,
+		"message":   "Webhook is configured correctly",
+	})
+	body, err := json.Marshal(event)
+	if err != nil {
+		return models.WebhookDelivery{}
+	}
+
+	delivery, _ := d.attempt(ctx, webhook, event, body, 1)
+	if err := d.store.AddDelivery(ctx, delivery); err != nil {
+		slog.ErrorContext(ctx, "webhooks: failed to record delivery", "delivery_id", delivery.ID, "error", err)
+	}
+	return delivery
+}
+
+// attempt makes a single delivery and reports whether a failure is worth
+// retrying
+func (d *WebhookDispatcher) attempt(ctx context.Context, webhook models.Webhook, event models.WebhookEvent, body []byte, attempt int) (models.WebhookDelivery, bool) {
+	delivery := models.WebhookDelivery{
+		ID:          utils.NewID(),
+		WebhookID:   webhook.ID,
+		EventID:     event.ID,
+		EventType:   event.Type,
+		URL:         webhook.URL,
+		Attempt:     attempt,
+		AttemptedAt: time.Now().UTC(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = fmt.Sprintf("failed to create request: %v", err)
+		return delivery, false
+	}
+
+	timestamp := strconv.FormatInt(delivery.AttemptedAt.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cheapest-flight-webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, event.Type)
+	req.Header.Set(WebhookIDHeader, event.ID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery, !errors.Is(err, ErrPrivateWebhookAddress)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	delivery.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		delivery.Success = true
+		return delivery, false
+	}
+
+	delivery.Error = fmt.Sprintf("receiver responded with status %d", resp.StatusCode)
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	return delivery, retry
+}
+
+// CheckURL resolves a webhook URL's host and rejects it unless every address
+// it resolves to is public or private destinations are allowed
+func (d *WebhookDispatcher) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if d.allowPrivate {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("webhook host %q could not be resolved", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !publicAddress(addr.IP) {
+			return ErrPrivateWebhookAddress
+		}
+	}
+	return nil
+}
+
+// checkWebhookDial is the dialer hook that refuses connections to
+// non-public addresses
+func checkWebhookDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicAddress(ip) {
+		return ErrPrivateWebhookAddress
+	}
+	return nil
+}
+
+// publicAddress reports whether ip is outside the loopback, link-local,
+// private and other non-routable ranges
+func publicAddress(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 signature of a delivery
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"cheapest-flight-backend/models"
+	"cheapest-flight-backend/store"
+)
+
+// receivedDelivery is one request seen by the test receiver
+type receivedDelivery struct {
+	header http.Header
+	body   []byte
+}
+
+// newTestReceiver starts a local webhook receiver that answers with the given
+// status codes in turn, then 200, and reports every request it gets
+func newTestReceiver(t *testing.T, statuses ...int) (*httptest.Server, <-chan receivedDelivery) {
+	t.Helper()
+	received := make(chan receivedDelivery, 16)
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedDelivery{header: r.Header.Clone(), body: body}
+
+		mu.Lock()
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+func waitForDelivery(t *testing.T, received <-chan receivedDelivery) receivedDelivery {
+	t.Helper()
+	select {
+	case d := <-received:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a webhook delivery")
+		return receivedDelivery{}
+	}
+}
+
+func TestWebhookPublishSignsAndRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, received := newTestReceiver(t, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	db := store.NewMemoryStore()
+	webhook := models.Webhook{
+		ID:        "hook-1",
+		UserID:    "user-1",
+		URL:       server.URL,
+		Events:    []string{models.EventPriceDrop},
+		Secret:    "test-secret",
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := db.SaveWebhook(ctx, webhook); err != nil {
+		t.Fatalf("SaveWebhook: %v", err)
+	}
+
+	dispatcher := NewWebhookDispatcher(db, 3, time.Millisecond, time.Second, true)
+	dispatcher.Start(ctx)
+	dispatcher.Publish(ctx, "user-1", models.EventPriceDrop, map[string]string{"route": "JFK-LAX"})
+
+	var eventID string
+	for attempt := 1; attempt <= 3; attempt++ {
+		d := waitForDelivery(t, received)
+
+		timestamp := d.header.Get(WebhookTimestampHeader)
+		want := "sha256=" + SignWebhookPayload(webhook.Secret, timestamp, d.body)
+		if got := d.header.Get(WebhookSignatureHeader); got != want {
+			t.Errorf("attempt %d: signature = %q, want %q", attempt, got, want)
+		}
+		if got := d.header.Get(WebhookEventHeader); got != models.EventPriceDrop {
+			t.Errorf("attempt %d: event header = %q, want %q", attempt, got, models.EventPriceDrop)
+		}
+
+		var event models.WebhookEvent
+		if err := json.Unmarshal(d.body, &event); err != nil {
+			t.Fatalf("attempt %d: invalid body: %v", attempt, err)
+		}
+		if eventID == "" {
+			eventID = event.ID
+		} else if event.ID != eventID {
+			t.Errorf("attempt %d: event ID changed from %s to %s", attempt, eventID, event.ID)
+		}
+	}
+
+	// The third attempt succeeded, so there are no more
+	select {
+	case <-received:
+		t.Fatal("delivery retried after the receiver accepted it")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries, err := db.ListDeliveries(ctx, webhook.ID, 10)
+		if err != nil {
+			t.Fatalf("ListDeliveries: %v", err)
+		}
+		if len(deliveries) == 3 {
+			if !deliveries[0].Success || deliveries[0].Attempt != 3 {
+				t.Errorf("latest delivery = %+v, want a successful third attempt", deliveries[0])
+			}
+			if deliveries[2].StatusCode != http.StatusInternalServerError {
+				t.Errorf("first delivery status = %d, want 500", deliveries[2].StatusCode)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("recorded %d deliveries, want 3", len(deliveries))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebhookPublishSkipsOtherOwners(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server, received := newTestReceiver(t)
+	db := store.NewMemoryStore()
+	if err := db.SaveWebhook(ctx, models.Webhook{
+		ID: "hook-1", UserID: "user-1", URL: server.URL,
+		Events: []string{models.EventSearchCompleted}, Secret: "s", CreatedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("SaveWebhook: %v", err)
+	}
+
+	dispatcher := NewWebhookDispatcher(db, 1, time.Millisecond, time.Second, true)
+	dispatcher.Start(ctx)
+	dispatcher.Publish(ctx, "user-2", models.EventSearchCompleted, nil)
+	dispatcher.Publish(ctx, "", models.EventSearchCompleted, nil)
+
+	select {
+	case <-received:
+		t.Fatal("event delivered to another user's webhook")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWebhookDispatcherRefusesPrivateDestinations(t *testing.T) {
+	ctx := context.Background()
+	server, received := newTestReceiver(t)
+	db := store.NewMemoryStore()
+	webhook := models.Webhook{ID: "hook-1", UserID: "user-1", URL: server.URL, Secret: "s"}
+
+	dispatcher := NewWebhookDispatcher(db, 3, time.Millisecond, time.Second, false)
+	if err := dispatcher.CheckURL(ctx, server.URL); !errors.Is(err, ErrPrivateWebhookAddress) {
+		t.Errorf("CheckURL(%s) = %v, want ErrPrivateWebhookAddress", server.URL, err)
+	}
+
+	// A URL registered before it resolved privately is still refused at
+	// dial time, without retries
+	delivery := dispatcher.Deliver(ctx, webhook, NewEvent(models.EventPing, nil))
+	if delivery.Success || delivery.Attempt != 1 {
+		t.Errorf("delivery = %+v, want one failed attempt", delivery)
+	}
+	select {
+	case <-received:
+		t.Fatal("private receiver was reached")
+	default:
+	}
+
+	allowing := NewWebhookDispatcher(db, 1, time.Millisecond, time.Second, true)
+	if err := allowing.CheckURL(ctx, server.URL); err != nil {
+		t.Errorf("CheckURL with private destinations allowed = %v", err)
+	}
+}
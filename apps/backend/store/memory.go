@@ -0,0 +1,351 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"cheapest-flight-backend/models"
+)
+
+// MemoryStore keeps everything in process memory. It is meant for tests and
+// throwaway environments: nothing survives a restart.
+type MemoryStore struct {
+	mu         sync.RWMutex
+	watches    map[string]models.PriceWatch
+	webhooks   map[string]models.Webhook
+	deliveries map[string][]models.WebhookDelivery // webhook ID -> newest first
+	prices     map[string][]models.PriceObservation
+	jobs       map[string]models.SearchJob
+	users      map[string]models.User
+	searches   map[string]models.SavedSearch
+	apiKeys    map[string]models.APIKey
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		watches:    make(map[string]models.PriceWatch),
+		webhooks:   make(map[string]models.Webhook),
+		deliveries: make(map[string][]models.WebhookDelivery),
+		prices:     make(map[string][]models.PriceObservation),
+		jobs:       make(map[string]models.SearchJob),
+		users:      make(map[string]models.User),
+		searches:   make(map[string]models.SavedSearch),
+		apiKeys:    make(map[string]models.APIKey),
+	}
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func (s *MemoryStore) ListWatches(ctx context.Context) ([]models.PriceWatch, error) {
+	return s.listWatches(func(models.PriceWatch) bool { return true }), nil
+}
+
+func (s *MemoryStore) ListUserWatches(ctx context.Context, userID string) ([]models.PriceWatch, error) {
+	return s.listWatches(func(watch models.PriceWatch) bool { return watch.UserID == userID }), nil
+}
+
+func (s *MemoryStore) listWatches(keep func(models.PriceWatch) bool) []models.PriceWatch {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	watches := make([]models.PriceWatch, 0, len(s.watches))
+	for _, watch := range s.watches {
+		if keep(watch) {
+			watches = append(watches, watch)
+		}
+	}
+	sort.Slice(watches, func(i, j int) bool {
+		if watches[i].CreatedAt.Equal(watches[j].CreatedAt) {
+			return watches[i].ID < watches[j].ID
+		}
+		return watches[i].CreatedAt.Before(watches[j].CreatedAt)
+	})
+	return watches
+}
+
+func (s *MemoryStore) GetWatch(ctx context.Context, id string) (models.PriceWatch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	watch, exists := s.watches[id]
+	if !exists {
+		return watch, ErrNotFound
+	}
+	return watch, nil
+}
+
+func (s *MemoryStore) SaveWatch(ctx context.Context, watch models.PriceWatch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	watch.Crossings = append([]models.PriceWatchCrossing{}, watch.Crossings...)
+	s.watches[watch.ID] = watch
+	return nil
+}
+
+func (s *MemoryStore) DeleteWatch(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.watches[id]; !exists {
+		return ErrNotFound
+	}
+	delete(s.watches, id)
+	return nil
+}
+
+func (s *MemoryStore) ListUserWebhooks(ctx context.Context, userID string) ([]models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhooks := make([]models.Webhook, 0, len(s.webhooks))
+	for _, webhook := range s.webhooks {
+		if webhook.UserID == userID {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		if webhooks[i].CreatedAt.Equal(webhooks[j].CreatedAt) {
+			return webhooks[i].ID < webhooks[j].ID
+		}
+		return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+	})
+	return webhooks, nil
+}
+
+func (s *MemoryStore) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhook, exists := s.webhooks[id]
+	if !exists {
+		return webhook, ErrNotFound
+	}
+	return webhook, nil
+}
+
+func (s *MemoryStore) SaveWebhook(ctx context.Context, webhook models.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook.Events = append([]string{}, webhook.Events...)
+	s.webhooks[webhook.ID] = webhook
+	return nil
+}
+
+func (s *MemoryStore) DeleteWebhook(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.webhooks[id]; !exists {
+		return ErrNotFound
+	}
+	delete(s.webhooks, id)
+	delete(s.deliveries, id)
+	return nil
+}
+
+func (s *MemoryStore) AddDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log := append([]models.WebhookDelivery{delivery}, s.deliveries[delivery.WebhookID]...)
+	if len(log) > maxDeliveriesPerWebhook {
+		log = log[:maxDeliveriesPerWebhook]
+	}
+	s.deliveries[delivery.WebhookID] = log
+	return nil
+}
+
+func (s *MemoryStore) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	log := s.deliveries[webhookID]
+	if limit > 0 && len(log) > limit {
+		log = log[:limit]
+	}
+	return append([]models.WebhookDelivery(nil), log...), nil
+}
+
+func (s *MemoryStore) RecordPrices(ctx context.Context, observations []models.PriceObservation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, obs := range observations {
+		key := priceKey(obs.Origin, obs.Destination, obs.TravelDate)
+		s.prices[key] = append(s.prices[key], obs)
+	}
+	return nil
+}
+
+func (s *MemoryStore) PriceHistory(ctx context.Context, origin, destination, travelDate string) ([]models.PriceObservation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]models.PriceObservation(nil), s.prices[priceKey(origin, destination, travelDate)]...), nil
+}
+
+func (s *MemoryStore) SaveJob(ctx context.Context, job models.SearchJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *MemoryStore) GetJob(ctx context.Context, id string) (models.SearchJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, exists := s.jobs[id]
+	if !exists {
+		return job, ErrNotFound
+	}
+	return job, nil
+}
+
+func (s *MemoryStore) DeleteExpiredJobs(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for id, job := range s.jobs {
+		if job.Status != models.JobQueued && job.Status != models.JobRunning && job.ExpiresAt.Before(before) {
+			delete(s.jobs, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *MemoryStore) CreateUser(ctx context.Context, user models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Email == user.Email {
+			return ErrConflict
+		}
+	}
+	s.users[user.ID] = user
+	return nil
+}
+
+func (s *MemoryStore) GetUser(ctx context.Context, id string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, exists := s.users[id]
+	if !exists {
+		return user, ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *MemoryStore) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return models.User{}, ErrNotFound
+}
+
+func (s *MemoryStore) ListSavedSearches(ctx context.Context, userID string) ([]models.SavedSearch, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var searches []models.SavedSearch
+	for _, search := range s.searches {
+		if search.UserID == userID {
+			searches = append(searches, search)
+		}
+	}
+	sort.Slice(searches, func(i, j int) bool {
+		if searches[i].CreatedAt.Equal(searches[j].CreatedAt) {
+			return searches[i].ID < searches[j].ID
+		}
+		return searches[i].CreatedAt.After(searches[j].CreatedAt)
+	})
+	return searches, nil
+}
+
+func (s *MemoryStore) SaveSearch(ctx context.Context, search models.SavedSearch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.searches[search.ID] = search
+	return nil
+}
+
+func (s *MemoryStore) DeleteSavedSearch(ctx context.Context, userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	search, exists := s.searches[id]
+	if !exists || search.UserID != userID {
+		return ErrNotFound
+	}
+	delete(s.searches, id)
+	return nil
+}
+
+func (s *MemoryStore) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]models.APIKey, 0, len(s.apiKeys))
+	for _, key := range s.apiKeys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+func (s *MemoryStore) GetAPIKey(ctx context.Context, id string) (models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, exists := s.apiKeys[id]
+	if !exists {
+		return key, ErrNotFound
+	}
+	return key, nil
+}
+
+func (s *MemoryStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.apiKeys {
+		if key.KeyHash == keyHash {
+			return key, nil
+		}
+	}
+	return models.APIKey{}, ErrNotFound
+}
+
+func (s *MemoryStore) SaveAPIKey(ctx context.Context, key models.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.apiKeys[key.ID] = key
+	return nil
+}
+
+func priceKey(origin, destination, travelDate string) string {
+	return origin + "-" + destination + "-" + travelDate
+}
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// migration is one versioned schema change. Versions must only ever be
+// appended; an applied migration is never edited.
+type migration struct {
+	version     int
+	description string
+	statements  []string
+}
+
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create price watches",
+		statements: []string{
+			`CREATE TABLE watches (
+				id              TEXT PRIMARY KEY,
+				origin          TEXT NOT NULL,
+				destination     TEXT NOT NULL,
+				travel_date     TEXT NOT NULL,
+				passengers      INTEGER NOT NULL,
+				max_price       REAL NOT NULL,
+				created_at      TEXT NOT NULL,
+				updated_at      TEXT NOT NULL,
+				last_checked_at TEXT,
+				last_best_price REAL,
+				last_currency   TEXT NOT NULL DEFAULT '',
+				last_error      TEXT NOT NULL DEFAULT '',
+				below_threshold INTEGER NOT NULL DEFAULT 0,
+				crossings       TEXT NOT NULL DEFAULT '[]'
+			)`,
+		},
+	},
+	{
+		version:     2,
+		description: "create webhooks and delivery log",
+		statements: []string{
+			`CREATE TABLE webhooks (
+				id         TEXT PRIMARY KEY,
+				url        TEXT NOT NULL,
+				events     TEXT NOT NULL,
+				secret     TEXT NOT NULL,
+				created_at TEXT NOT NULL
+			)`,
+			`CREATE TABLE webhook_deliveries (
+				id           TEXT PRIMARY KEY,
+				webhook_id   TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+				event_id     TEXT NOT NULL,
+				event_type   TEXT NOT NULL,
+				url          TEXT NOT NULL,
+				attempt      INTEGER NOT NULL,
+				status_code  INTEGER NOT NULL DEFAULT 0,
+				success      INTEGER NOT NULL DEFAULT 0,
+				error        TEXT NOT NULL DEFAULT '',
+				duration_ms  INTEGER NOT NULL DEFAULT 0,
+				attempted_at TEXT NOT NULL
+			)`,
+			`CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, attempted_at)`,
+		},
+	},
+	{
+		version:     3,
+		description: "create price history",
+		statements: []string{
+			`CREATE TABLE price_observations (
+				id          INTEGER PRIMARY KEY AUTOINCREMENT,
+				origin      TEXT NOT NULL,
+				destination TEXT NOT NULL,
+				travel_date TEXT NOT NULL,
+				observed_at TEXT NOT NULL,
+				price       REAL NOT NULL,
+				currency    TEXT NOT NULL,
+				carrier     TEXT NOT NULL,
+				stops       INTEGER NOT NULL
+			)`,
+			`CREATE INDEX idx_price_observations_route ON price_observations (origin, destination, travel_date)`,
+		},
+	},
+	{
+		version:     4,
+		description: "create search jobs",
+		statements: []string{
+			`CREATE TABLE search_jobs (
+				id         TEXT PRIMARY KEY,
+				status     TEXT NOT NULL,
+				job        TEXT NOT NULL,
+				expires_at TEXT NOT NULL
+			)`,
+			`CREATE INDEX idx_search_jobs_expires ON search_jobs (expires_at)`,
+		},
+	},
+	{
+		version:     5,
+		description: "create users and saved searches",
+		statements: []string{
+			`CREATE TABLE users (
+				id            TEXT PRIMARY KEY,
+				email         TEXT NOT NULL UNIQUE,
+				password_hash TEXT NOT NULL,
+				created_at    TEXT NOT NULL
+			)`,
+			`CREATE TABLE saved_searches (
+				id          TEXT PRIMARY KEY,
+				user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				name        TEXT NOT NULL DEFAULT '',
+				origin      TEXT NOT NULL,
+				destination TEXT NOT NULL,
+				travel_date TEXT NOT NULL,
+				passengers  INTEGER NOT NULL,
+				created_at  TEXT NOT NULL
+			)`,
+			`CREATE INDEX idx_saved_searches_user ON saved_searches (user_id, created_at)`,
+		},
+	},
+	{
+		version:     6,
+		description: "create api keys",
+		statements: []string{
+			`CREATE TABLE api_keys (
+				id                  TEXT PRIMARY KEY,
+				name                TEXT NOT NULL,
+				prefix              TEXT NOT NULL,
+				key_hash            TEXT NOT NULL UNIQUE,
+				requests_per_minute INTEGER NOT NULL,
+				daily_searches      INTEGER NOT NULL,
+				created_at          TEXT NOT NULL,
+				revoked_at          TEXT
+			)`,
+		},
+	},
+	{
+		version:     7,
+		description: "add nearby airport radius to saved searches",
+		statements: []string{
+			`ALTER TABLE saved_searches ADD COLUMN nearby_radius_km INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		version:     8,
+		description: "add owners to watches",
+		statements: []string{
+			// Watches created before accounts belong to no one and are not checked
+			`ALTER TABLE watches ADD COLUMN user_id TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX idx_watches_user ON watches (user_id, created_at)`,
+		},
+	},
+	{
+		version:     9,
+		description: "add owners to webhooks",
+		statements: []string{
+			// Webhooks registered before accounts belong to no one
+			`ALTER TABLE webhooks ADD COLUMN user_id TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX idx_webhooks_user ON webhooks (user_id, created_at)`,
+		},
+	},
+	{
+		version:     10,
+		description: "store search job owners in their own column",
+		statements: []string{
+			// The owner is no longer part of the job's JSON, so lift it out of
+			// jobs stored before this migration
+			`ALTER TABLE search_jobs ADD COLUMN user_id TEXT NOT NULL DEFAULT ''`,
+			`UPDATE search_jobs SET user_id = COALESCE(json_extract(job, '$.userId'), '')`,
+		},
+	},
+}
+
+// migrate applies every migration newer than the database's current
+// version, each in its own transaction
+func migrate(ctx context.Context, db *sql.DB) error {
+	return migrateTo(ctx, db, migrations[len(migrations)-1].version)
+}
+
+// migrateTo applies the migrations newer than the database's current version
+// up to and including target
+func migrateTo(ctx context.Context, db *sql.DB, target int) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at  TEXT NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current || m.version > target {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.description, err)
+		}
+		slog.InfoContext(ctx, "applied database migration", "version", m.version, "description", m.description)
+	}
+
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range m.statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)`,
+		m.version, m.description, formatTime(time.Now())); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,175 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func latestVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// openRaw opens a SQLite database without migrating it
+func openRaw(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_foreign_keys=on", path))
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func schemaVersion(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		t.Fatalf("read schema version: %v", err)
+	}
+	return version
+}
+
+func TestMigrationVersionsAreSequential(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Errorf("migration %d has version %d, want %d", i, m.version, i+1)
+		}
+	}
+}
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "fresh.db")
+	s, err := OpenSQLite(ctx, path)
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	if got := schemaVersion(t, s.db); got != latestVersion() {
+		t.Errorf("schema version = %d, want %d", got, latestVersion())
+	}
+	s.Close()
+
+	// Reopening a current database applies nothing
+	s, err = OpenSQLite(ctx, path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+	var applied int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatalf("count migrations: %v", err)
+	}
+	if applied != len(migrations) {
+		t.Errorf("%d migrations recorded, want %d", applied, len(migrations))
+	}
+}
+
+func TestMigrateFromEveryVersion(t *testing.T) {
+	ctx := context.Background()
+	for from := 0; from < latestVersion(); from++ {
+		t.Run(fmt.Sprintf("from v%d", from), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "old.db")
+			if err := migrateTo(ctx, openRaw(t, path), from); err != nil {
+				t.Fatalf("migrate to v%d: %v", from, err)
+			}
+
+			s, err := OpenSQLite(ctx, path)
+			if err != nil {
+				t.Fatalf("OpenSQLite from v%d: %v", from, err)
+			}
+			defer s.Close()
+			if got := schemaVersion(t, s.db); got != latestVersion() {
+				t.Errorf("schema version = %d, want %d", got, latestVersion())
+			}
+		})
+	}
+}
+
+// TestMigrateKeepsRowsWithoutOwners checks that watches and webhooks created
+// before they had owners survive the owner columns being added, with no owner
+func TestMigrateKeepsRowsWithoutOwners(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	db := openRaw(t, path)
+
+	// The last schema before watches had owners
+	if err := migrateTo(ctx, db, 7); err != nil {
+		t.Fatalf("migrate to v7: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO watches (id, origin, destination, travel_date, passengers, max_price, created_at, updated_at)
+		VALUES ('watch-1', 'JFK', 'LAX', '2026-12-01', 1, 300, '2026-10-01T00:00:00Z', '2026-10-01T00:00:00Z')`); err != nil {
+		t.Fatalf("insert legacy watch: %v", err)
+	}
+
+	// One version behind the latest
+	if err := migrateTo(ctx, db, latestVersion()-1); err != nil {
+		t.Fatalf("migrate to v%d: %v", latestVersion()-1, err)
+	}
+	if _, err := db.Exec(`INSERT INTO webhooks (id, url, events, secret, created_at)
+		VALUES ('hook-1', 'https://example.com/', '["price.drop"]', 's', '2026-10-01T00:00:00Z')`); err != nil {
+		t.Fatalf("insert legacy webhook: %v", err)
+	}
+	db.Close()
+
+	s, err := OpenSQLite(ctx, path)
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	defer s.Close()
+
+	watch, err := s.GetWatch(ctx, "watch-1")
+	if err != nil {
+		t.Fatalf("GetWatch: %v", err)
+	}
+	if watch.UserID != "" || watch.Origin != "JFK" || len(watch.Crossings) != 0 {
+		t.Errorf("legacy watch = %+v, want an unowned JFK watch with no crossings", watch)
+	}
+
+	webhook, err := s.GetWebhook(ctx, "hook-1")
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	if webhook.UserID != "" || len(webhook.Events) != 1 {
+		t.Errorf("legacy webhook = %+v, want an unowned webhook with one event", webhook)
+	}
+}
+
+// TestMigrateMovesJobOwnersOutOfJSON checks that jobs stored while their
+// owner was part of the JSON keep it
+func TestMigrateMovesJobOwnersOutOfJSON(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	db := openRaw(t, path)
+	if err := migrateTo(ctx, db, 9); err != nil {
+		t.Fatalf("migrate to v9: %v", err)
+	}
+	for id, job := range map[string]string{
+		"owned":     `{"id":"owned","status":"completed","userId":"user-1"}`,
+		"anonymous": `{"id":"anonymous","status":"completed"}`,
+	} {
+		if _, err := db.Exec(`INSERT INTO search_jobs (id, status, job, expires_at) VALUES (?, 'completed', ?, '2099-01-01T00:00:00Z')`,
+			id, job); err != nil {
+			t.Fatalf("insert job %s: %v", id, err)
+		}
+	}
+	db.Close()
+
+	s, err := OpenSQLite(ctx, path)
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	defer s.Close()
+
+	for id, want := range map[string]string{"owned": "user-1", "anonymous": ""} {
+		job, err := s.GetJob(ctx, id)
+		if err != nil {
+			t.Fatalf("GetJob(%s): %v", id, err)
+		}
+		if job.UserID != want {
+			t.Errorf("job %s owner = %q, want %q", id, job.UserID, want)
+		}
+	}
+}
@@ -0,0 +1,579 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"cheapest-flight-backend/models"
+)
+
+// SQLiteStore is the embedded SQLite implementation of Store
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens (creating if needed) the database at path and migrates it
+// to the latest schema
+func OpenSQLite(ctx context.Context, path string) (*SQLiteStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+const watchColumns = `id, user_id, origin, destination, travel_date, passengers, max_price, created_at, updated_at,
+	last_checked_at, last_best_price, last_currency, last_error, below_threshold, crossings`
+
+func (s *SQLiteStore) ListWatches(ctx context.Context) ([]models.PriceWatch, error) {
+	return s.queryWatches(ctx, `SELECT `+watchColumns+` FROM watches ORDER BY created_at, id`)
+}
+
+func (s *SQLiteStore) ListUserWatches(ctx context.Context, userID string) ([]models.PriceWatch, error) {
+	return s.queryWatches(ctx, `SELECT `+watchColumns+` FROM watches WHERE user_id = ? ORDER BY created_at, id`, userID)
+}
+
+func (s *SQLiteStore) queryWatches(ctx context.Context, query string, args ...interface{}) ([]models.PriceWatch, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var watches []models.PriceWatch
+	for rows.Next() {
+		watch, err := scanWatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		watches = append(watches, watch)
+	}
+	return watches, rows.Err()
+}
+
+func (s *SQLiteStore) GetWatch(ctx context.Context, id string) (models.PriceWatch, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+watchColumns+` FROM watches WHERE id = ?`, id)
+	watch, err := scanWatch(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return watch, ErrNotFound
+	}
+	return watch, err
+}
+
+func (s *SQLiteStore) SaveWatch(ctx context.Context, watch models.PriceWatch) error {
+	crossings, err := json.Marshal(watch.Crossings)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO watches (`+watchColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			origin = excluded.origin,
+			destination = excluded.destination,
+			travel_date = excluded.travel_date,
+			passengers = excluded.passengers,
+			max_price = excluded.max_price,
+			updated_at = excluded.updated_at,
+			last_checked_at = excluded.last_checked_at,
+			last_best_price = excluded.last_best_price,
+			last_currency = excluded.last_currency,
+			last_error = excluded.last_error,
+			below_threshold = excluded.below_threshold,
+			crossings = excluded.crossings`,
+		watch.ID, watch.UserID, watch.Origin, watch.Destination, watch.Date, watch.Passengers, watch.MaxPrice,
+		formatTime(watch.CreatedAt), formatTime(watch.UpdatedAt), formatTimePtr(watch.LastCheckedAt),
+		watch.LastBestPrice, watch.LastCurrency, watch.LastError, watch.BelowThreshold, string(crossings))
+	return err
+}
+
+func (s *SQLiteStore) DeleteWatch(ctx context.Context, id string) error {
+	return deleteByID(ctx, s.db, "watches", id)
+}
+
+const webhookColumns = `id, user_id, url, events, secret, created_at`
+
+func (s *SQLiteStore) ListUserWebhooks(ctx context.Context, userID string) ([]models.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE user_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (s *SQLiteStore) GetWebhook(ctx context.Context, id string) (models.Webhook, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id)
+	webhook, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhook, ErrNotFound
+	}
+	return webhook, err
+}
+
+func (s *SQLiteStore) SaveWebhook(ctx context.Context, webhook models.Webhook) error {
+	events, err := json.Marshal(webhook.Events)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO webhooks (`+webhookColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			url = excluded.url,
+			events = excluded.events,
+			secret = excluded.secret`,
+		webhook.ID, webhook.UserID, webhook.URL, string(events), webhook.Secret, formatTime(webhook.CreatedAt))
+	return err
+}
+
+func (s *SQLiteStore) DeleteWebhook(ctx context.Context, id string) error {
+	// Deliveries go with it through ON DELETE CASCADE
+	return deleteByID(ctx, s.db, "webhooks", id)
+}
+
+func (s *SQLiteStore) AddDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO webhook_deliveries
+		(id, webhook_id, event_id, event_type, url, attempt, status_code, success, error, duration_ms, attempted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.ID, d.WebhookID, d.EventID, d.EventType, d.URL, d.Attempt, d.StatusCode, d.Success, d.Error,
+		d.DurationMs, formatTime(d.AttemptedAt)); err != nil {
+		return err
+	}
+
+	// Drop the oldest attempts once the webhook's log is over the cap
+	if _, err := tx.ExecContext(ctx, `DELETE FROM webhook_deliveries
+		WHERE webhook_id = ? AND id NOT IN (
+			SELECT id FROM webhook_deliveries WHERE webhook_id = ?
+			ORDER BY attempted_at DESC LIMIT ?
+		)`, d.WebhookID, d.WebhookID, maxDeliveriesPerWebhook); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]models.WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = maxDeliveriesPerWebhook
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, webhook_id, event_id, event_type, url, attempt, status_code,
+		success, error, duration_ms, attempted_at
+		FROM webhook_deliveries WHERE webhook_id = ?
+		ORDER BY attempted_at DESC LIMIT ?`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		var attemptedAt string
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.URL, &d.Attempt, &d.StatusCode,
+			&d.Success, &d.Error, &d.DurationMs, &attemptedAt); err != nil {
+			return nil, err
+		}
+		if d.AttemptedAt, err = parseTime(attemptedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *SQLiteStore) RecordPrices(ctx context.Context, observations []models.PriceObservation) error {
+	if len(observations) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO price_observations
+		(origin, destination, travel_date, observed_at, price, currency, carrier, stops)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, obs := range observations {
+		if _, err := stmt.ExecContext(ctx, obs.Origin, obs.Destination, obs.TravelDate, formatTime(obs.ObservedAt),
+			obs.Price, obs.Currency, obs.Carrier, obs.Stops); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) PriceHistory(ctx context.Context, origin, destination, travelDate string) ([]models.PriceObservation, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT origin, destination, travel_date, observed_at, price, currency, carrier, stops
+		FROM price_observations
+		WHERE origin = ? AND destination = ? AND travel_date = ?
+		ORDER BY observed_at`, origin, destination, travelDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var observations []models.PriceObservation
+	for rows.Next() {
+		var obs models.PriceObservation
+		var observedAt string
+		if err := rows.Scan(&obs.Origin, &obs.Destination, &obs.TravelDate, &observedAt,
+			&obs.Price, &obs.Currency, &obs.Carrier, &obs.Stops); err != nil {
+			return nil, err
+		}
+		if obs.ObservedAt, err = parseTime(observedAt); err != nil {
+			return nil, err
+		}
+		observations = append(observations, obs)
+	}
+	return observations, rows.Err()
+}
+
+func (s *SQLiteStore) SaveJob(ctx context.Context, job models.SearchJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO search_jobs (id, status, job, expires_at, user_id)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			status = excluded.status,
+			job = excluded.job,
+			expires_at = excluded.expires_at,
+			user_id = excluded.user_id`,
+		job.ID, job.Status, string(data), formatTime(job.ExpiresAt), job.UserID)
+	return err
+}
+
+func (s *SQLiteStore) GetJob(ctx context.Context, id string) (models.SearchJob, error) {
+	var job models.SearchJob
+	var data, userID string
+	err := s.db.QueryRowContext(ctx, `SELECT job, user_id FROM search_jobs WHERE id = ?`, id).Scan(&data, &userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return job, ErrNotFound
+	}
+	if err != nil {
+		return job, err
+	}
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return job, err
+	}
+	job.UserID = userID
+	return job, nil
+}
+
+func (s *SQLiteStore) DeleteExpiredJobs(ctx context.Context, before time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM search_jobs
+		WHERE expires_at < ? AND status NOT IN (?, ?)`,
+		formatTime(before), models.JobQueued, models.JobRunning)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+func (s *SQLiteStore) CreateUser(ctx context.Context, user models.User) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO users (id, email, password_hash, created_at) VALUES (?, ?, ?, ?)`,
+		user.ID, user.Email, user.PasswordHash, formatTime(user.CreatedAt))
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return ErrConflict
+	}
+	return err
+}
+
+func (s *SQLiteStore) GetUser(ctx context.Context, id string) (models.User, error) {
+	return s.getUser(ctx, `SELECT id, email, password_hash, created_at FROM users WHERE id = ?`, id)
+}
+
+func (s *SQLiteStore) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
+	return s.getUser(ctx, `SELECT id, email, password_hash, created_at FROM users WHERE email = ?`, email)
+}
+
+func (s *SQLiteStore) getUser(ctx context.Context, query string, arg string) (models.User, error) {
+	var user models.User
+	var createdAt string
+	err := s.db.QueryRowContext(ctx, query, arg).Scan(&user.ID, &user.Email, &user.PasswordHash, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, ErrNotFound
+	}
+	if err != nil {
+		return user, err
+	}
+	user.CreatedAt, err = parseTime(createdAt)
+	return user, err
+}
+
+func (s *SQLiteStore) ListSavedSearches(ctx context.Context, userID string) ([]models.SavedSearch, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, user_id, name, origin, destination, travel_date, passengers, nearby_radius_km, created_at
+		FROM saved_searches WHERE user_id = ?
+		ORDER BY created_at DESC, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var searches []models.SavedSearch
+	for rows.Next() {
+		var search models.SavedSearch
+		var createdAt string
+		if err := rows.Scan(&search.ID, &search.UserID, &search.Name, &search.Query.Origin, &search.Query.Destination,
+			&search.Query.Date, &search.Query.Passengers, &search.Query.NearbyRadiusKm, &createdAt); err != nil {
+			return nil, err
+		}
+		if search.CreatedAt, err = parseTime(createdAt); err != nil {
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+	return searches, rows.Err()
+}
+
+func (s *SQLiteStore) SaveSearch(ctx context.Context, search models.SavedSearch) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO saved_searches
+		(id, user_id, name, origin, destination, travel_date, passengers, nearby_radius_km, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		search.ID, search.UserID, search.Name, search.Query.Origin, search.Query.Destination,
+		search.Query.Date, search.Query.Passengers, search.Query.NearbyRadiusKm, formatTime(search.CreatedAt))
+	return err
+}
+
+func (s *SQLiteStore) DeleteSavedSearch(ctx context.Context, userID, id string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM saved_searches WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, requests_per_minute, daily_searches, created_at, revoked_at`
+
+func (s *SQLiteStore) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *SQLiteStore) GetAPIKey(ctx context.Context, id string) (models.APIKey, error) {
+	return s.getAPIKey(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id)
+}
+
+func (s *SQLiteStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	return s.getAPIKey(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ?`, keyHash)
+}
+
+func (s *SQLiteStore) getAPIKey(ctx context.Context, query, arg string) (models.APIKey, error) {
+	key, err := scanAPIKey(s.db.QueryRowContext(ctx, query, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return key, ErrNotFound
+	}
+	return key, err
+}
+
+func (s *SQLiteStore) SaveAPIKey(ctx context.Context, key models.APIKey) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO api_keys (`+apiKeyColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			name = excluded.name,
+			requests_per_minute = excluded.requests_per_minute,
+			daily_searches = excluded.daily_searches,
+			revoked_at = excluded.revoked_at`,
+		key.ID, key.Name, key.Prefix, key.KeyHash, key.RequestsPerMinute, key.DailySearches,
+		formatTime(key.CreatedAt), formatTimePtr(key.RevokedAt))
+	return err
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWatch(row scanner) (models.PriceWatch, error) {
+	var watch models.PriceWatch
+	var createdAt, updatedAt, crossings string
+	var lastCheckedAt sql.NullString
+	var lastBestPrice sql.NullFloat64
+
+	if err := row.Scan(&watch.ID, &watch.UserID, &watch.Origin, &watch.Destination, &watch.Date, &watch.Passengers, &watch.MaxPrice,
+		&createdAt, &updatedAt, &lastCheckedAt, &lastBestPrice, &watch.LastCurrency, &watch.LastError,
+		&watch.BelowThreshold, &crossings); err != nil {
+		return watch, err
+	}
+
+	var err error
+	if watch.CreatedAt, err = parseTime(createdAt); err != nil {
+		return watch, err
+	}
+	if watch.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return watch, err
+	}
+	if lastCheckedAt.Valid {
+		t, err := parseTime(lastCheckedAt.String)
+		if err != nil {
+			return watch, err
+		}
+		watch.LastCheckedAt = &t
+	}
+	if lastBestPrice.Valid {
+		price := lastBestPrice.Float64
+		watch.LastBestPrice = &price
+	}
+	if err := json.Unmarshal([]byte(crossings), &watch.Crossings); err != nil {
+		return watch, fmt.Errorf("invalid crossings for watch %s: %w", watch.ID, err)
+	}
+
+	return watch, nil
+}
+
+func scanWebhook(row scanner) (models.Webhook, error) {
+	var webhook models.Webhook
+	var events, createdAt string
+
+	if err := row.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, &events, &webhook.Secret, &createdAt); err != nil {
+		return webhook, err
+	}
+
+	var err error
+	if webhook.CreatedAt, err = parseTime(createdAt); err != nil {
+		return webhook, err
+	}
+	if err := json.Unmarshal([]byte(events), &webhook.Events); err != nil {
+		return webhook, fmt.Errorf("invalid events for webhook %s: %w", webhook.ID, err)
+	}
+
+	return webhook, nil
+}
+
+func scanAPIKey(row scanner) (models.APIKey, error) {
+	var key models.APIKey
+	var createdAt string
+	var revokedAt sql.NullString
+
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.KeyHash, &key.RequestsPerMinute, &key.DailySearches,
+		&createdAt, &revokedAt); err != nil {
+		return key, err
+	}
+
+	var err error
+	if key.CreatedAt, err = parseTime(createdAt); err != nil {
+		return key, err
+	}
+	if revokedAt.Valid {
+		t, err := parseTime(revokedAt.String)
+		if err != nil {
+			return key, err
+		}
+		key.RevokedAt = &t
+	}
+
+	return key, nil
+}
+
+// deleteByID removes a row by primary key, reporting ErrNotFound when there
+// was nothing to delete
+func deleteByID(ctx context.Context, db *sql.DB, table, id string) error {
+	result, err := db.ExecContext(ctx, `DELETE FROM `+table+` WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Timestamps are stored as RFC 3339 text in UTC so they sort lexically
+const timeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func formatTimePtr(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+func parseTime(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, value)
+}
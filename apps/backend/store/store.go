@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cheapest-flight-backend/models"
+)
+
+// ErrNotFound is returned when a record does not exist
+var ErrNotFound = errors.New("record not found")
+
+// ErrConflict is returned when a record would violate a uniqueness constraint
+var ErrConflict = errors.New("record already exists")
+
+// Supported storage backends
+const (
+	DriverSQLite = "sqlite"
+	DriverMemory = "memory"
+)
+
+// WatchStore persists price watch subscriptions
+type WatchStore interface {
+	ListWatches(ctx context.Context) ([]models.PriceWatch, error)
+	ListUserWatches(ctx context.Context, userID string) ([]models.PriceWatch, error)
+	GetWatch(ctx context.Context, id string) (models.PriceWatch, error)
+	SaveWatch(ctx context.Context, watch models.PriceWatch) error
+	DeleteWatch(ctx context.Context, id string) error
+}
+
+// WebhookStore persists webhook registrations and their delivery log
+type WebhookStore interface {
+	ListUserWebhooks(ctx context.Context, userID string) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (models.Webhook, error)
+	SaveWebhook(ctx context.Context, webhook models.Webhook) error
+	DeleteWebhook(ctx context.Context, id string) error
+	AddDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]models.WebhookDelivery, error)
+}
+
+// PriceStore persists observed fares
+type PriceStore interface {
+	RecordPrices(ctx context.Context, observations []models.PriceObservation) error
+	PriceHistory(ctx context.Context, origin, destination, travelDate string) ([]models.PriceObservation, error)
+}
+
+// JobStore persists asynchronous search jobs so results outlive a restart
+type JobStore interface {
+	SaveJob(ctx context.Context, job models.SearchJob) error
+	GetJob(ctx context.Context, id string) (models.SearchJob, error)
+	DeleteExpiredJobs(ctx context.Context, before time.Time) (int, error)
+}
+
+// UserStore persists user accounts and their saved searches. Saved searches
+// are always scoped to their owner.
+type UserStore interface {
+	CreateUser(ctx context.Context, user models.User) error
+	GetUser(ctx context.Context, id string) (models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (models.User, error)
+	ListSavedSearches(ctx context.Context, userID string) ([]models.SavedSearch, error)
+	SaveSearch(ctx context.Context, search models.SavedSearch) error
+	DeleteSavedSearch(ctx context.Context, userID, id string) error
+}
+
+// APIKeyStore persists API keys issued to third-party consumers
+type APIKeyStore interface {
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	GetAPIKey(ctx context.Context, id string) (models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error)
+	SaveAPIKey(ctx context.Context, key models.APIKey) error
+}
+
+// Store is the full persistence layer used by the backend
+type Store interface {
+	WatchStore
+	WebhookStore
+	PriceStore
+	JobStore
+	UserStore
+	APIKeyStore
+	Close() error
+}
+
+// maxDeliveriesPerWebhook caps the delivery log kept for each webhook
+const maxDeliveriesPerWebhook = 100
+
+// Open returns the store for the configured driver. SQLite databases are
+// migrated to the latest schema before they are returned.
+func Open(ctx context.Context, driver, path string) (Store, error) {
+	switch driver {
+	case DriverSQLite:
+		return OpenSQLite(ctx, path)
+	case DriverMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
+}
@@ -0,0 +1,435 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cheapest-flight-backend/models"
+)
+
+// storeFactories opens each Store implementation fresh for a test
+var storeFactories = []struct {
+	name string
+	open func(t *testing.T) Store
+}{
+	{"memory", func(t *testing.T) Store { return NewMemoryStore() }},
+	{"sqlite", func(t *testing.T) Store {
+		s, err := OpenSQLite(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatalf("OpenSQLite: %v", err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s
+	}},
+}
+
+// TestStoreContract runs the same checks against every implementation, so
+// the in-memory store can stand in for SQLite in other tests
+func TestStoreContract(t *testing.T) {
+	contracts := []struct {
+		name string
+		run  func(t *testing.T, s Store)
+	}{
+		{"watches", testWatches},
+		{"webhooks", testWebhooks},
+		{"users", testUsers},
+		{"saved searches", testSavedSearches},
+		{"jobs", testJobs},
+		{"price history", testPriceHistory},
+		{"api keys", testAPIKeys},
+	}
+
+	for _, factory := range storeFactories {
+		for _, contract := range contracts {
+			t.Run(factory.name+"/"+contract.name, func(t *testing.T) {
+				contract.run(t, factory.open(t))
+			})
+		}
+	}
+}
+
+// base is a fixed instant with a sub-second part, to check that stored times
+// keep their precision
+var base = time.Date(2026, 10, 1, 12, 30, 15, 123456789, time.UTC)
+
+func testWatches(t *testing.T, s Store) {
+	ctx := context.Background()
+	checked := base.Add(time.Hour)
+	bestPrice := 412.5
+	watch := models.PriceWatch{
+		ID:     "watch-1",
+		UserID: "user-1",
+		PriceWatchRequest: models.PriceWatchRequest{
+			Origin: "JFK", Destination: "LAX", Date: "2026-12-01", Passengers: 2, MaxPrice: 450,
+		},
+		CreatedAt:      base,
+		UpdatedAt:      base,
+		LastCheckedAt:  &checked,
+		LastBestPrice:  &bestPrice,
+		LastCurrency:   "USD",
+		BelowThreshold: true,
+		Crossings: []models.PriceWatchCrossing{{
+			At: checked, Direction: models.CrossedBelow, Price: bestPrice, Currency: "USD", Threshold: 450,
+			Route: []string{"JFK", "LAX"},
+		}},
+	}
+	other := models.PriceWatch{
+		ID:                "watch-2",
+		UserID:            "user-2",
+		PriceWatchRequest: models.PriceWatchRequest{Origin: "LHR", Destination: "CDG", Date: "2026-12-02", Passengers: 1, MaxPrice: 100},
+		CreatedAt:         base.Add(time.Minute),
+		UpdatedAt:         base.Add(time.Minute),
+		Crossings:         []models.PriceWatchCrossing{},
+	}
+	for _, w := range []models.PriceWatch{watch, other} {
+		if err := s.SaveWatch(ctx, w); err != nil {
+			t.Fatalf("SaveWatch(%s): %v", w.ID, err)
+		}
+	}
+
+	got, err := s.GetWatch(ctx, watch.ID)
+	if err != nil {
+		t.Fatalf("GetWatch: %v", err)
+	}
+	assertSameJSON(t, "GetWatch", got, watch)
+
+	all, err := s.ListWatches(ctx)
+	if err != nil {
+		t.Fatalf("ListWatches: %v", err)
+	}
+	assertIDs(t, "ListWatches", watchIDs(all), "watch-1", "watch-2")
+
+	mine, err := s.ListUserWatches(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("ListUserWatches: %v", err)
+	}
+	assertIDs(t, "ListUserWatches", watchIDs(mine), "watch-1")
+
+	// Saving again updates in place
+	watch.MaxPrice = 500
+	watch.UpdatedAt = base.Add(2 * time.Hour)
+	if err := s.SaveWatch(ctx, watch); err != nil {
+		t.Fatalf("SaveWatch update: %v", err)
+	}
+	if got, _ := s.GetWatch(ctx, watch.ID); got.MaxPrice != 500 || !got.UpdatedAt.Equal(watch.UpdatedAt) {
+		t.Errorf("after update got max price %v, updated %v", got.MaxPrice, got.UpdatedAt)
+	}
+
+	if err := s.DeleteWatch(ctx, watch.ID); err != nil {
+		t.Fatalf("DeleteWatch: %v", err)
+	}
+	if _, err := s.GetWatch(ctx, watch.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetWatch after delete = %v, want ErrNotFound", err)
+	}
+	if err := s.DeleteWatch(ctx, watch.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second DeleteWatch = %v, want ErrNotFound", err)
+	}
+}
+
+func testWebhooks(t *testing.T, s Store) {
+	ctx := context.Background()
+	webhook := models.Webhook{
+		ID:        "hook-1",
+		UserID:    "user-1",
+		URL:       "https://example.com/hook",
+		Events:    []string{models.EventPriceDrop, models.EventSearchCompleted},
+		Secret:    "secret",
+		CreatedAt: base,
+	}
+	other := models.Webhook{ID: "hook-2", UserID: "user-2", URL: "https://example.org/", Events: []string{models.EventPriceDrop}, Secret: "s", CreatedAt: base}
+	for _, w := range []models.Webhook{webhook, other} {
+		if err := s.SaveWebhook(ctx, w); err != nil {
+			t.Fatalf("SaveWebhook(%s): %v", w.ID, err)
+		}
+	}
+
+	got, err := s.GetWebhook(ctx, webhook.ID)
+	if err != nil {
+		t.Fatalf("GetWebhook: %v", err)
+	}
+	assertSameJSON(t, "GetWebhook", got, webhook)
+
+	mine, err := s.ListUserWebhooks(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("ListUserWebhooks: %v", err)
+	}
+	if len(mine) != 1 || mine[0].ID != webhook.ID {
+		t.Errorf("ListUserWebhooks = %+v, want only %s", mine, webhook.ID)
+	}
+
+	for i := 1; i <= 3; i++ {
+		delivery := models.WebhookDelivery{
+			ID: "delivery-" + string(rune('0'+i)), WebhookID: webhook.ID, EventID: "event-1",
+			EventType: models.EventPriceDrop, URL: webhook.URL, Attempt: i, StatusCode: 500,
+			Error: "receiver responded with status 500", DurationMs: 12, AttemptedAt: base.Add(time.Duration(i) * time.Second),
+		}
+		if err := s.AddDelivery(ctx, delivery); err != nil {
+			t.Fatalf("AddDelivery: %v", err)
+		}
+	}
+	deliveries, err := s.ListDeliveries(ctx, webhook.ID, 2)
+	if err != nil {
+		t.Fatalf("ListDeliveries: %v", err)
+	}
+	if len(deliveries) != 2 || deliveries[0].Attempt != 3 || deliveries[1].Attempt != 2 {
+		t.Errorf("ListDeliveries = %+v, want attempts 3 and 2, newest first", deliveries)
+	}
+
+	if err := s.DeleteWebhook(ctx, webhook.ID); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if _, err := s.GetWebhook(ctx, webhook.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetWebhook after delete = %v, want ErrNotFound", err)
+	}
+	if deliveries, _ := s.ListDeliveries(ctx, webhook.ID, 10); len(deliveries) != 0 {
+		t.Errorf("deliveries outlived their webhook: %+v", deliveries)
+	}
+}
+
+func testUsers(t *testing.T, s Store) {
+	ctx := context.Background()
+	user := models.User{ID: "user-1", Email: "a@example.com", PasswordHash: "hash", CreatedAt: base}
+	if err := s.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := s.CreateUser(ctx, models.User{ID: "user-2", Email: user.Email, PasswordHash: "x", CreatedAt: base}); !errors.Is(err, ErrConflict) {
+		t.Errorf("CreateUser with a taken email = %v, want ErrConflict", err)
+	}
+
+	for name, get := range map[string]func() (models.User, error){
+		"GetUser":        func() (models.User, error) { return s.GetUser(ctx, user.ID) },
+		"GetUserByEmail": func() (models.User, error) { return s.GetUserByEmail(ctx, user.Email) },
+	} {
+		got, err := get()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got.PasswordHash != user.PasswordHash {
+			t.Errorf("%s password hash = %q, want %q", name, got.PasswordHash, user.PasswordHash)
+		}
+		assertSameJSON(t, name, got, user)
+	}
+
+	if _, err := s.GetUser(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetUser(missing) = %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetUserByEmail(ctx, "missing@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetUserByEmail(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func testSavedSearches(t *testing.T, s Store) {
+	ctx := context.Background()
+	for _, id := range []string{"user-1", "user-2"} {
+		if err := s.CreateUser(ctx, models.User{ID: id, Email: id + "@example.com", PasswordHash: "h", CreatedAt: base}); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+
+	older := models.SavedSearch{
+		ID: "search-1", UserID: "user-1", Name: "Holidays", CreatedAt: base,
+		Query: models.FlightSearchRequest{Origin: "JFK", Destination: "LAX", Date: "2026-12-01", Passengers: 2, NearbyRadiusKm: 100},
+	}
+	newer := models.SavedSearch{
+		ID: "search-2", UserID: "user-1", CreatedAt: base.Add(time.Hour),
+		Query: models.FlightSearchRequest{Origin: "LHR", Destination: "CDG", Date: "2026-12-02", Passengers: 1},
+	}
+	theirs := models.SavedSearch{
+		ID: "search-3", UserID: "user-2", CreatedAt: base,
+		Query: models.FlightSearchRequest{Origin: "SIN", Destination: "HKG", Date: "2026-12-03", Passengers: 1},
+	}
+	for _, search := range []models.SavedSearch{older, newer, theirs} {
+		if err := s.SaveSearch(ctx, search); err != nil {
+			t.Fatalf("SaveSearch(%s): %v", search.ID, err)
+		}
+	}
+
+	searches, err := s.ListSavedSearches(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("ListSavedSearches: %v", err)
+	}
+	if len(searches) != 2 {
+		t.Fatalf("ListSavedSearches returned %d searches, want 2", len(searches))
+	}
+	assertSameJSON(t, "newest saved search", searches[0], newer)
+	assertSameJSON(t, "oldest saved search", searches[1], older)
+	if searches[1].UserID != "user-1" {
+		t.Errorf("saved search owner = %q, want user-1", searches[1].UserID)
+	}
+
+	if err := s.DeleteSavedSearch(ctx, "user-2", older.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting another user's search = %v, want ErrNotFound", err)
+	}
+	if err := s.DeleteSavedSearch(ctx, "user-1", older.ID); err != nil {
+		t.Fatalf("DeleteSavedSearch: %v", err)
+	}
+	if searches, _ := s.ListSavedSearches(ctx, "user-1"); len(searches) != 1 || searches[0].ID != newer.ID {
+		t.Errorf("after delete ListSavedSearches = %+v, want only %s", searches, newer.ID)
+	}
+}
+
+func testJobs(t *testing.T, s Store) {
+	ctx := context.Background()
+	completed := base.Add(time.Minute)
+	done := models.SearchJob{
+		ID:                "job-1",
+		UserID:            "user-1",
+		Status:            models.JobCompleted,
+		Progress:          1,
+		CompletedBranches: []string{"direct", "one_stop"},
+		Query:             models.FlightSearchRequest{Origin: "JFK", Destination: "LAX", Date: "2026-12-01", Passengers: 1},
+		Flights: []models.Flight{{
+			ID: "f1", Origin: "JFK", Destination: "LAX", Date: "2026-12-01", Price: 199, Currency: "USD",
+			Airline: "AA", Duration: "6h", Route: []string{"JFK", "LAX"}, DurationMinutes: 360,
+		}},
+		Total:       1,
+		CreatedAt:   base,
+		CompletedAt: &completed,
+		ExpiresAt:   base.Add(30 * time.Minute),
+	}
+	running := models.SearchJob{
+		ID: "job-2", Status: models.JobRunning, CompletedBranches: []string{}, Flights: []models.Flight{},
+		CreatedAt: base, ExpiresAt: base.Add(30 * time.Minute),
+	}
+	for _, job := range []models.SearchJob{done, running} {
+		if err := s.SaveJob(ctx, job); err != nil {
+			t.Fatalf("SaveJob(%s): %v", job.ID, err)
+		}
+	}
+
+	got, err := s.GetJob(ctx, done.ID)
+	if err != nil {
+		t.Fatalf("GetJob: %v", err)
+	}
+	if got.UserID != done.UserID {
+		t.Errorf("job owner = %q, want %q", got.UserID, done.UserID)
+	}
+	assertSameJSON(t, "GetJob", got, done)
+
+	// Only finished jobs past their expiry are removed
+	deleted, err := s.DeleteExpiredJobs(ctx, base.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteExpiredJobs: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("DeleteExpiredJobs deleted %d jobs, want 1", deleted)
+	}
+	if _, err := s.GetJob(ctx, done.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetJob of an expired job = %v, want ErrNotFound", err)
+	}
+	if _, err := s.GetJob(ctx, running.ID); err != nil {
+		t.Errorf("running job was deleted: %v", err)
+	}
+}
+
+func testPriceHistory(t *testing.T, s Store) {
+	ctx := context.Background()
+	observations := []models.PriceObservation{
+		{Origin: "JFK", Destination: "LAX", TravelDate: "2026-12-01", ObservedAt: base, Price: 210, Currency: "USD", Carrier: "AA", Stops: 0},
+		{Origin: "JFK", Destination: "LAX", TravelDate: "2026-12-01", ObservedAt: base.Add(time.Hour), Price: 189.99, Currency: "USD", Carrier: "DL", Stops: 1},
+		{Origin: "JFK", Destination: "LAX", TravelDate: "2026-12-02", ObservedAt: base, Price: 150, Currency: "USD", Carrier: "UA", Stops: 0},
+	}
+	if err := s.RecordPrices(ctx, observations); err != nil {
+		t.Fatalf("RecordPrices: %v", err)
+	}
+	if err := s.RecordPrices(ctx, nil); err != nil {
+		t.Fatalf("RecordPrices(nil): %v", err)
+	}
+
+	history, err := s.PriceHistory(ctx, "JFK", "LAX", "2026-12-01")
+	if err != nil {
+		t.Fatalf("PriceHistory: %v", err)
+	}
+	assertSameJSON(t, "PriceHistory", history, observations[:2])
+
+	if history, _ := s.PriceHistory(ctx, "LAX", "JFK", "2026-12-01"); len(history) != 0 {
+		t.Errorf("PriceHistory of an unseen route = %+v, want none", history)
+	}
+}
+
+func testAPIKeys(t *testing.T, s Store) {
+	ctx := context.Background()
+	key := models.APIKey{
+		ID: "key-1", Name: "partner", Prefix: "cf_abcd", KeyHash: "hash-1",
+		RequestsPerMinute: 60, DailySearches: 500, CreatedAt: base,
+	}
+	if err := s.SaveAPIKey(ctx, key); err != nil {
+		t.Fatalf("SaveAPIKey: %v", err)
+	}
+
+	byHash, err := s.GetAPIKeyByHash(ctx, key.KeyHash)
+	if err != nil {
+		t.Fatalf("GetAPIKeyByHash: %v", err)
+	}
+	if byHash.KeyHash != key.KeyHash {
+		t.Errorf("key hash = %q, want %q", byHash.KeyHash, key.KeyHash)
+	}
+	assertSameJSON(t, "GetAPIKeyByHash", byHash, key)
+
+	revoked := base.Add(time.Hour)
+	key.RevokedAt = &revoked
+	if err := s.SaveAPIKey(ctx, key); err != nil {
+		t.Fatalf("SaveAPIKey revoke: %v", err)
+	}
+	got, err := s.GetAPIKey(ctx, key.ID)
+	if err != nil {
+		t.Fatalf("GetAPIKey: %v", err)
+	}
+	if !got.Revoked() {
+		t.Error("revocation was not saved")
+	}
+
+	keys, err := s.ListAPIKeys(ctx)
+	if err != nil {
+		t.Fatalf("ListAPIKeys: %v", err)
+	}
+	if len(keys) != 1 {
+		t.Errorf("ListAPIKeys returned %d keys, want 1", len(keys))
+	}
+	if _, err := s.GetAPIKeyByHash(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetAPIKeyByHash(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+// assertSameJSON compares values by their JSON encoding, which ignores time
+// zone representation but not the instant
+func assertSameJSON(t *testing.T, what string, got, want interface{}) {
+	t.Helper()
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("%s mismatch\n got: %s\nwant: %s", what, gotJSON, wantJSON)
+	}
+}
+
+func assertIDs(t *testing.T, what string, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", what, got, want)
+		return
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("%s = %v, want %v", what, got, want)
+			return
+		}
+	}
+}
+
+func watchIDs(watches []models.PriceWatch) []string {
+	ids := make([]string, len(watches))
+	for i, w := range watches {
+		ids[i] = w.ID
+	}
+	return ids
+}
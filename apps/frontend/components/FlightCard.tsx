@@ -1,6 +1,6 @@
 "use client";
 
-import { Flight } from "@/lib/types";
+import { Flight, FlightTag } from "@/lib/types";
 import {
   formatPrice,
   formatDate,
@@ -11,6 +11,27 @@ import {
   calculateSavings,
 } from "@/lib/utils";
 
+const tagStyles: Record<
+  FlightTag,
+  { label: string; title: string; className: string }
+> = {
+  cheapest: {
+    label: "Cheapest",
+    title: "The lowest price of all results",
+    className: "bg-green-100 text-green-800",
+  },
+  fastest: {
+    label: "Fastest",
+    title: "The shortest total travel time of all results",
+    className: "bg-blue-100 text-blue-800",
+  },
+  best_value: {
+    label: "Best value",
+    title: "The best balance of price and travel time",
+    className: "bg-purple-100 text-purple-800",
+  },
+};
+
 interface FlightCardProps {
   flight: Flight;
   isBestDeal?: boolean;
@@ -126,6 +147,15 @@ export default function FlightCard({
               >
                 {getStopsText(flight.stops)}
               </span>
+              {flight.tags?.map((tag) => (
+                <span
+                  key={tag}
+                  title={tagStyles[tag].title}
+                  className={`px-2 py-1 rounded-full text-xs font-medium ${tagStyles[tag].className}`}
+                >
+                  {tagStyles[tag].label}
+                </span>
+              ))}
               <span>{formatDate(flight.date)}</span>
               {flight.stops > 0 && (
                 <div className="flex items-center space-x-2">
@@ -1,7 +1,8 @@
 "use client";
 
-import { useState } from "react";
+import { useEffect, useState } from "react";
 import AirportInput from "./AirportInput";
+import { apiClient } from "@/lib/api";
 import { SearchFormData, SearchFormErrors } from "@/lib/types";
 import { getTomorrowDate, validateAirportCode } from "@/lib/utils";
 
@@ -20,6 +21,35 @@ export default function SearchForm({ onSearch, isLoading }: SearchFormProps) {
 
   const [errors, setErrors] = useState<SearchFormErrors>({});
 
+  // Pre-fill the origin with the airport nearest to the browser, unless the
+  // user has already typed one
+  useEffect(() => {
+    if (!("geolocation" in navigator)) return;
+    let cancelled = false;
+    navigator.geolocation.getCurrentPosition(
+      async ({ coords }) => {
+        try {
+          const { airports } = await apiClient.getNearestAirports(
+            coords.latitude,
+            coords.longitude
+          );
+          if (!cancelled && airports.length > 0) {
+            setFormData((prev) =>
+              prev.origin ? prev : { ...prev, origin: airports[0].iata }
+            );
+          }
+        } catch {
+          // No airport nearby or the API is unreachable; leave origin empty
+        }
+      },
+      () => {}, // Permission denied; leave origin empty
+      { maximumAge: 10 * 60 * 1000, timeout: 10000 }
+    );
+    return () => {
+      cancelled = true;
+    };
+  }, []);
+
   const validateForm = (): boolean => {
     const newErrors: SearchFormErrors = {};
 
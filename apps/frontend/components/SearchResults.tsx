@@ -135,8 +135,9 @@ export default function SearchResults({
         return a.price - b.price;
       case "duration":
         // Convert duration to minutes for comparison
-        const getDurationMinutes = (duration: string) => {
-          const match = duration.match(/(\d+)h\s*(\d+)?m?/);
+        const getDurationMinutes = (flight: Flight) => {
+          if (flight.durationMinutes) return flight.durationMinutes;
+          const match = flight.duration.match(/(\d+)h\s*(\d+)?m?/);
           if (match) {
             const hours = parseInt(match[1]) || 0;
             const minutes = parseInt(match[2]) || 0;
@@ -144,7 +145,7 @@ export default function SearchResults({
           }
           return 0;
         };
-        return getDurationMinutes(a.duration) - getDurationMinutes(b.duration);
+        return getDurationMinutes(a) - getDurationMinutes(b);
       case "stops":
         return a.stops - b.stops;
       default:
@@ -218,6 +219,12 @@ export default function SearchResults({
           </div>
         </div>
 
+        <p className="text-sm text-gray-600 mb-4">
+          Every option here is the best at something: none is beaten on
+          price, travel time and stops all at once. Look for the Cheapest,
+          Fastest and Best value tags to compare the tradeoffs.
+        </p>
+
         {/* Flight Cards */}
         <div className="space-y-4">
           {displayedFlights.map((flight, index) => (
@@ -1,4 +1,9 @@
-import { FlightSearchRequest, FlightSearchResponse, Airport } from "./types";
+import {
+  FlightSearchRequest,
+  FlightSearchResponse,
+  Airport,
+  NearbyAirport,
+} from "./types";
 
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080";
 
@@ -59,6 +64,16 @@ class APIClient {
     );
   }
 
+  async getNearestAirports(
+    lat: number,
+    lon: number,
+    limit = 1
+  ): Promise<{ airports: NearbyAirport[]; total: number }> {
+    return this.request<{ airports: NearbyAirport[]; total: number }>(
+      `/api/airports/nearest?lat=${lat}&lon=${lon}&limit=${limit}`
+    );
+  }
+
   async healthCheck(): Promise<{ status: string; service: string }> {
     return this.request<{ status: string; service: string }>("/health");
   }
@@ -5,8 +5,12 @@ export interface Airport {
   iata: string;
   icao: string;
   airport: string;
-  latitude: string;
-  longitude: string;
+  latitude: number;
+  longitude: number;
+}
+
+export interface NearbyAirport extends Airport {
+  distance_km: number;
 }
 
 // Flight Search types
@@ -29,13 +33,20 @@ export interface Flight {
   stops: number;
   route: string[];
   bookingUrl?: string;
+  durationMinutes?: number;
+  tags?: FlightTag[];
 }
 
+// Why a result is worth a look; a flight can have several
+export type FlightTag = "cheapest" | "fastest" | "best_value";
+
 export interface FlightSearchResponse {
   flights: Flight[];
   message?: string;
   total: number;
   query: FlightSearchRequest;
+  upstreamCalls: number;
+  degraded?: boolean;
 }
 
 // Form types